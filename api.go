@@ -0,0 +1,197 @@
+package mondo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondohttp"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrAttachmentTooLarge is returned when attaching files larger than
+// MaxAttachmentSize.
+var ErrAttachmentTooLarge = errors.New("mondo: Attachment too large")
+
+// MaxAttachmentSize is the largest file, in bytes, which AttachFile reads into
+// memory to upload.
+const MaxAttachmentSize int64 = 10 << 20
+
+// The methods below pair each mondohttp request with its mondodomain response.
+// Authenticated requests are made with an empty access token, relying on the
+// Client's Auth to provide one.
+
+// Ping checks that the API is reachable.
+func (c *Client) Ping(ctx context.Context) (*mondodomain.Ping, error) {
+	ping := new(mondodomain.Ping)
+	err := c.DoIntoContext(ctx, mondohttp.NewPingRequest(), ping)
+	if err != nil {
+		return nil, err
+	}
+	return ping, nil
+}
+
+// WhoAmI returns the identity the Client is authenticated as.
+func (c *Client) WhoAmI(ctx context.Context) (*mondodomain.Identity, error) {
+	identity := new(mondodomain.Identity)
+	err := c.DoIntoContext(ctx, mondohttp.NewWhoAmIRequest(""), identity)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// Accounts lists the user's accounts.
+func (c *Client) Accounts(ctx context.Context) ([]mondodomain.Account, error) {
+	accounts := new(mondodomain.AccountsResponse)
+	err := c.DoIntoContext(ctx, mondohttp.NewAccountsRequest(""), accounts)
+	if err != nil {
+		return nil, err
+	}
+	return accounts.Accounts, nil
+}
+
+// Balance returns an account's current balance.
+func (c *Client) Balance(ctx context.Context, accountID string) (*mondodomain.Balance, error) {
+	balance := new(mondodomain.Balance)
+	err := c.DoIntoContext(ctx, mondohttp.NewBalanceRequest("", accountID), balance)
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// Transaction returns a single transaction, optionally with its merchant
+// details expanded.
+func (c *Client) Transaction(ctx context.Context, transactionID string, expandMerchants bool) (*mondodomain.Transaction, error) {
+	tran := new(mondodomain.TransactionResponse)
+	err := c.DoIntoContext(ctx, mondohttp.NewTransactionRequest("", transactionID, expandMerchants), tran)
+	if err != nil {
+		return nil, err
+	}
+	return &tran.Transaction, nil
+}
+
+// Annotate updates the metadata of a transaction, returning the updated
+// transaction. Metadata keys given empty values are removed.
+func (c *Client) Annotate(ctx context.Context, transactionID string, metadata map[string]string) (*mondodomain.Transaction, error) {
+	tran := new(mondodomain.TransactionResponse)
+	err := c.DoIntoContext(ctx, mondohttp.NewAnnotateTransactionRequest("", transactionID, metadata), tran)
+	if err != nil {
+		return nil, err
+	}
+	return &tran.Transaction, nil
+}
+
+// CreateFeedItem adds an item to an account's feed. See
+// mondohttp.NewCreateFeedItemRequest for the parameters.
+func (c *Client) CreateFeedItem(ctx context.Context, accountID, itemType, itemURL string, params map[string]string) error {
+	return c.DoIntoContext(ctx, mondohttp.NewCreateFeedItemRequest("", accountID, itemType, itemURL, params), &struct{}{})
+}
+
+// RegisterWebhook registers a URL to receive the account's events.
+func (c *Client) RegisterWebhook(ctx context.Context, accountID, webhookURL string) (*mondodomain.Webhook, error) {
+	webhook := new(mondodomain.WebhookResponse)
+	err := c.DoIntoContext(ctx, mondohttp.NewRegisterWebhookRequest("", accountID, webhookURL), webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook.Webhook, nil
+}
+
+// Webhooks lists the webhooks registered to an account.
+func (c *Client) Webhooks(ctx context.Context, accountID string) ([]mondodomain.Webhook, error) {
+	webhooks := new(mondodomain.WebhooksResponse)
+	err := c.DoIntoContext(ctx, mondohttp.NewWebhooksRequest("", accountID), webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks.Webhooks, nil
+}
+
+// DeleteWebhook removes a webhook.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID string) error {
+	return c.DoIntoContext(ctx, mondohttp.NewDeleteWebhookRequest("", webhookID), &struct{}{})
+}
+
+// EnsureWebhook registers a URL to receive the account's events unless it is
+// already registered, returning the webhook either way.
+func (c *Client) EnsureWebhook(ctx context.Context, accountID, webhookURL string) (*mondodomain.Webhook, error) {
+	webhooks, err := c.Webhooks(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range webhooks {
+		if webhook.URL == webhookURL {
+			return &webhook, nil
+		}
+	}
+	return c.RegisterWebhook(ctx, accountID, webhookURL)
+}
+
+// AttachFile uploads a file and attaches it to a transaction, returning the
+// registered attachment. The file is named after the transaction; use
+// AttachNamedFile to choose its name.
+func (c *Client) AttachFile(ctx context.Context, transactionID string, file io.Reader, contentType string) (*mondodomain.Attachment, error) {
+	return c.AttachNamedFile(ctx, transactionID, transactionID, file, contentType)
+}
+
+// AttachNamedFile uploads a file of up to MaxAttachmentSize bytes with the
+// given name and attaches it to a transaction, returning the registered
+// attachment. The upload is made with the Client's UploadClient, through its
+// middleware and Retry policy, but not its Auth or Limiter, which are for the
+// API alone.
+func (c *Client) AttachNamedFile(ctx context.Context, transactionID, fileName string, file io.Reader, contentType string) (*mondodomain.Attachment, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(file, MaxAttachmentSize+1))
+	if err != nil {
+		return nil, WrapError(err, "Failed to read attachment", nil, nil)
+	}
+	if int64(len(body)) > MaxAttachmentSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	// Request somewhere to put the file.
+	upload := new(mondodomain.AttachmentUpload)
+	err = c.DoIntoContext(ctx, mondohttp.NewAttachmentUploadRequest("", fileName, contentType), upload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload the file.
+	req, err := http.NewRequest("PUT", upload.UploadURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, WrapError(err, "Invalid attachment upload URL", nil, nil)
+	}
+	req.Header.Set("Content-Type", contentType)
+	uploader := *c
+	uploader.HTTPClient = c.uploadClient()
+	uploader.Auth = nil
+	uploader.Limiter = nil
+	resp, err := uploader.DoContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	// Attach the uploaded file.
+	attachment := new(mondodomain.AttachmentResponse)
+	err = c.DoIntoContext(ctx, mondohttp.NewRegisterAttachmentRequest("", transactionID, upload.FileURL, contentType), attachment)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment.Attachment, nil
+}
+
+// DeregisterAttachment removes an attachment from its transaction.
+func (c *Client) DeregisterAttachment(ctx context.Context, attachmentID string) error {
+	return c.DoIntoContext(ctx, mondohttp.NewDeregisterAttachmentRequest("", attachmentID), &struct{}{})
+}
+
+func (c *Client) uploadClient() httpclient {
+	if c.UploadClient == nil {
+		return http.DefaultClient
+	}
+	return c.UploadClient
+}
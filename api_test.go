@@ -0,0 +1,92 @@
+package mondo_test
+
+import (
+	"context"
+	"github.com/icio/mondo"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClient_Methods(t *testing.T) {
+	var method, uri, contentType, body, authorization string
+	client := &mondo.Client{
+		HTTPClient: mondo.DoFunc(func(req *http.Request) (*http.Response, error) {
+			method, uri = req.Method, req.URL.RequestURI()
+			contentType = req.Header.Get("Content-Type")
+			authorization = req.Header.Get("Authorization")
+			body = ""
+			if req.Body != nil {
+				b, _ := ioutil.ReadAll(req.Body)
+				body = string(b)
+			}
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{}`))}, nil
+		}),
+		Auth: mondo.NewAccessTokenAuth("token"),
+	}
+	ctx := context.Background()
+
+	const form = "application/x-www-form-urlencoded"
+	tests := []struct {
+		call        func() error
+		method      string
+		uri         string
+		contentType string
+		body        string
+	}{
+		{
+			func() error { _, err := client.WhoAmI(ctx); return err },
+			"GET", "/ping/whoami", "", "",
+		},
+		{
+			func() error { _, err := client.Accounts(ctx); return err },
+			"GET", "/accounts", "", "",
+		},
+		{
+			func() error { _, err := client.Balance(ctx, "acc_1"); return err },
+			"GET", "/balance?account_id=acc_1", "", "",
+		},
+		{
+			func() error { _, err := client.Transaction(ctx, "tx_1", true); return err },
+			"GET", "/transactions/tx_1?expand%5B%5D=merchant", "", "",
+		},
+		{
+			func() error { _, err := client.Annotate(ctx, "tx_1", map[string]string{"trip": "weekly"}); return err },
+			"PATCH", "/transactions/tx_1", form, "metadata%5Btrip%5D=weekly",
+		},
+		{
+			func() error {
+				return client.CreateFeedItem(ctx, "acc_1", "basic", "https://a.com", map[string]string{"title": "Hi"})
+			},
+			"POST", "/feed", form, "account_id=acc_1&params%5Btitle%5D=Hi&type=basic&url=https%3A%2F%2Fa.com",
+		},
+		{
+			func() error { _, err := client.RegisterWebhook(ctx, "acc_1", "https://a.com/hook"); return err },
+			"POST", "/webhooks", form, "account_id=acc_1&url=https%3A%2F%2Fa.com%2Fhook",
+		},
+		{
+			func() error { _, err := client.Webhooks(ctx, "acc_1"); return err },
+			"GET", "/webhooks?account_id=acc_1", "", "",
+		},
+		{
+			func() error { return client.DeleteWebhook(ctx, "webhook_1") },
+			"DELETE", "/webhooks/webhook_1", "", "",
+		},
+		{
+			func() error { return client.DeregisterAttachment(ctx, "attach_1") },
+			"POST", "/attachment/deregister", form, "id=attach_1",
+		},
+	}
+	for _, test := range tests {
+		if err := test.call(); err != nil {
+			t.Fatalf("%s %s: %s", test.method, test.uri, err)
+		}
+		if method != test.method || uri != test.uri || contentType != test.contentType || body != test.body {
+			t.Errorf("Expected %s %s (%q) %q but got %s %s (%q) %q", test.method, test.uri, test.contentType, test.body, method, uri, contentType, body)
+		}
+		if authorization != "Bearer token" {
+			t.Errorf("%s %s: expected the Auth's token but got %q", test.method, test.uri, authorization)
+		}
+	}
+}
@@ -1,24 +1,59 @@
 package mondo
 
 import (
+	"context"
 	"errors"
 	"github.com/icio/mondo/mondodomain"
 	"github.com/icio/mondo/mondohttp"
 	"sync"
+	"time"
 )
 
 // ErrNoCredentials indicates when UserAuth doesn't have credentials to auth.
 var ErrNoCredentials = errors.New("mondo: No credentials for generating access token")
 
+// DefaultRefreshSkew is how long before its expiry an access token is
+// refreshed, when UserAuth.RefreshSkew is unset.
+const DefaultRefreshSkew = time.Minute
+
+// refreshRetryDelay is how long KeepFresh waits after a failed refresh.
+const refreshRetryDelay = 10 * time.Second
+
+// minRefreshInterval is the least time between refreshes made ahead of an
+// access token's expiry.
+const minRefreshInterval = 5 * time.Second
+
 // UserAuth provides the access token for a single user-account, requesting
 // a new access token using a refresh token or username/password when required.
-// Thread-safe.
+// Access tokens with a known Expiry are refreshed ahead of it, otherwise they
+// are refreshed once the API rejects them. When a Store is given, tokens are
+// loaded from it before refreshing and saved to it after. A refreshed token
+// which can't be saved, including when another process has saved a token in
+// the meantime (ErrTokenConflict), is still used; the failure is reported to
+// OnEvent, and the Store's token is adopted when next loaded. OnEvent, if set,
+// is called as tokens are refreshed or revoked, and when refreshes or saves
+// fail. Thread-safe.
 type UserAuth struct {
 	ClientID     string
 	ClientSecret string
 	AccessToken  string
 	RefreshToken string
+	Expiry       time.Time
+	RefreshSkew  time.Duration
+	Store        TokenStore
+	OnEvent      func(AuthEvent)
 	Lock         sync.RWMutex
+
+	// lifetime is that of the access token, when known from its response.
+	lifetime time.Duration
+	// lastRefresh is when a refresh was last attempted.
+	lastRefresh time.Time
+	// stored is the token last loaded from or saved to the Store.
+	stored *StoredToken
+	// reauthErr is set once a refresh has been rejected.
+	reauthErr *ReauthorizationError
+	// events are those awaiting reporting to OnEvent.
+	events []AuthEvent
 }
 
 // NewAccessTokenAuth prepares an auth with existing access token (without the Bearer prefix).
@@ -40,9 +75,15 @@ func NewClientAccessTokenAuth(clientID, clientSecret, accessToken, refreshToken
 // Get returns an access token (e.g. "Bearer xyz..."), making authentication
 // requests using the credentials available to it, where necessary.
 func (auth *UserAuth) Get(invalidate bool, client *Client) (string, error) {
+	return auth.GetContext(context.Background(), invalidate, client)
+}
+
+// GetContext returns an access token as Get, bound to the given context.
+// Cancelling the context aborts any refresh request in progress.
+func (auth *UserAuth) GetContext(ctx context.Context, invalidate bool, client *Client) (string, error) {
 	// Attempt to reuse an existing access token.
 	auth.Lock.RLock()
-	if !invalidate && auth.AccessToken != "" {
+	if !invalidate && auth.AccessToken != "" && !auth.expiring() {
 		auth.Lock.RUnlock()
 		return auth.AccessToken, nil
 	}
@@ -51,33 +92,304 @@ func (auth *UserAuth) Get(invalidate bool, client *Client) (string, error) {
 	existingToken := auth.AccessToken
 	auth.Lock.RUnlock()
 	auth.Lock.Lock()
-	defer auth.Lock.Unlock()
+	defer auth.unlock()
+
+	// Pick up any token saved by other processes.
+	if err := auth.load(); err != nil {
+		return "", err
+	}
 
 	// Check if the token was refreshed elsewhere.
-	if auth.AccessToken != "" && existingToken != auth.AccessToken {
+	if auth.AccessToken != "" && existingToken != auth.AccessToken && !auth.expiring() {
+		return auth.AccessToken, nil
+	}
+
+	// Tokens which are only expiring remain usable should we fail to refresh,
+	// in which case the failure is reported to OnEvent and the refresh retried
+	// no sooner than minRefreshInterval.
+	if !invalidate && auth.AccessToken != "" && time.Now().Before(auth.Expiry) {
+		if auth.canRefresh() && time.Since(auth.lastRefresh) >= minRefreshInterval {
+			if err := auth.refresh(ctx, client); err != nil && ctx.Err() != nil {
+				return "", wrapContextError(err, "Token refresh cancelled", nil, nil)
+			}
+		}
 		return auth.AccessToken, nil
 	}
 
 	// Invalidate any existing access token.
 	auth.AccessToken = ""
-	var err error
+	if !auth.canRefresh() {
+		return "", ErrNoCredentials
+	}
+	if err := auth.refresh(ctx, client); err != nil {
+		return "", err
+	}
+
+	return auth.AccessToken, nil
+}
+
+// KeepFresh refreshes the access token ahead of each expiry until the context
+// is done, so that requests needn't wait on refreshes. Tokens of unknown
+// expiry are refreshed immediately to learn it. Failed refreshes are retried
+// after a delay, unless reauthorization is required, in which case the
+// ReauthorizationError is returned. Intended to be run in its own goroutine.
+func (auth *UserAuth) KeepFresh(ctx context.Context, client *Client) error {
+	for {
+		auth.Lock.RLock()
+		canRefresh := auth.canRefresh()
+		wait := time.Duration(0)
+		if !auth.Expiry.IsZero() {
+			wait = auth.Expiry.Add(-auth.skew()).Sub(time.Now())
+		}
+		if minWait := auth.lastRefresh.Add(minRefreshInterval).Sub(time.Now()); wait < minWait {
+			wait = minWait
+		}
+		auth.Lock.RUnlock()
 
+		if !canRefresh {
+			return ErrNoCredentials
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+
+		auth.Lock.Lock()
+		err := auth.load()
+		if err == nil && (auth.Expiry.IsZero() || auth.expiring()) {
+			err = auth.refresh(ctx, client)
+		}
+		unknownExpiry := auth.Expiry.IsZero()
+		auth.unlock()
+
+		if reauthErr, ok := err.(*ReauthorizationError); ok {
+			return reauthErr
+		} else if err != nil {
+			if err := sleep(ctx, refreshRetryDelay); err != nil {
+				return err
+			}
+		} else if unknownExpiry {
+			// We'd only be refreshing continuously.
+			return nil
+		}
+	}
+}
+
+// canRefresh reports whether the auth has the credentials to refresh its
+// access token. Requires the read lock.
+func (auth *UserAuth) canRefresh() bool {
+	return auth.ClientID != "" && auth.ClientSecret != "" && auth.RefreshToken != ""
+}
+
+// expiring reports whether the access token is within RefreshSkew of its
+// expiry. Requires the read lock.
+func (auth *UserAuth) expiring() bool {
+	return !auth.Expiry.IsZero() && !time.Now().Add(auth.skew()).Before(auth.Expiry)
+}
+
+// skew returns RefreshSkew, or its default, limited to half of the access
+// token's lifetime so that short-lived tokens aren't refreshed continuously.
+// Requires the read lock.
+func (auth *UserAuth) skew() time.Duration {
+	skew := auth.RefreshSkew
+	if skew <= 0 {
+		skew = DefaultRefreshSkew
+	}
+	if half := auth.lifetime / 2; half > 0 && skew > half {
+		skew = half
+	}
+	return skew
+}
+
+// refresh exchanges the refresh token for a new access token, leaving the
+// auth unchanged on failure. Once the API has rejected a refresh, no more are
+// attempted. The new token is kept even if it can't be saved to the Store,
+// reporting an AuthSaveFailed event. Requires the lock.
+func (auth *UserAuth) refresh(ctx context.Context, client *Client) error {
+	if auth.reauthErr != nil {
+		return auth.reauthErr
+	}
+
+	auth.lastRefresh = time.Now()
 	token := new(mondodomain.Token)
-	if auth.ClientID != "" && auth.ClientSecret != "" && auth.RefreshToken != "" {
-		// Refresh the access token.
-		err = client.DoInto(
-			mondohttp.NewRefreshAccessRequest(auth.ClientID, auth.ClientSecret, auth.RefreshToken),
-			token,
-		)
-	} else {
-		err = ErrNoCredentials
+	err := client.DoIntoContext(
+		ctx,
+		mondohttp.NewRefreshAccessRequest(auth.ClientID, auth.ClientSecret, auth.RefreshToken),
+		token,
+	)
+	if err != nil {
+		if refreshRejected(err) {
+			auth.reauthErr = &ReauthorizationError{cause: err}
+			auth.record(AuthRefreshFailed, err, true)
+			return auth.reauthErr
+		}
+		if ctx.Err() == nil {
+			auth.record(AuthRefreshFailed, err, false)
+		}
+		return err
+	}
+
+	auth.setToken(token)
+	auth.record(AuthRefreshed, nil, false)
+	if err := auth.save(); err != nil {
+		auth.record(AuthSaveFailed, err, false)
 	}
+	return nil
+}
+
+// load adopts the Store's token if it has changed since it was last loaded or
+// saved. Requires the lock.
+func (auth *UserAuth) load() error {
+	if auth.Store == nil {
+		return nil
+	}
+	stored, err := auth.Store.Load()
 	if err != nil {
-		return "", err
+		return WrapError(err, "Failed to load stored token", nil, nil)
+	}
+	if sameToken(stored, auth.stored) {
+		return nil
+	}
+
+	auth.stored = stored
+	auth.reauthErr = nil
+	if stored == nil {
+		stored = &StoredToken{}
+	}
+	auth.AccessToken = stored.AccessToken
+	auth.RefreshToken = stored.RefreshToken
+	auth.Expiry = stored.Expiry
+	auth.lifetime = 0
+	return nil
+}
+
+// save writes the current token to the Store in place of the token last
+// loaded or saved. Should another process have saved a token since, theirs is
+// kept and ErrTokenConflict returned. Requires the lock.
+func (auth *UserAuth) save() error {
+	if auth.Store == nil {
+		return nil
+	}
+	next := &StoredToken{
+		AccessToken:  auth.AccessToken,
+		RefreshToken: auth.RefreshToken,
+		Expiry:       auth.Expiry,
+	}
+	if err := auth.Store.CompareAndSwap(auth.stored, next); err != nil {
+		return WrapError(err, "Failed to save token", nil, nil)
 	}
+	auth.stored = next
+	return nil
+}
 
+// setToken stores the credentials of a token response. Requires the lock.
+func (auth *UserAuth) setToken(token *mondodomain.Token) {
+	auth.reauthErr = nil
 	auth.AccessToken = token.TokenType + " " + token.AccessToken
 	auth.RefreshToken = token.RefreshToken
+	auth.Expiry = time.Time{}
+	auth.lifetime = time.Duration(token.ExpiresIn) * time.Second
+	if token.ExpiresIn > 0 {
+		auth.Expiry = time.Now().Add(auth.lifetime)
+	}
+}
 
-	return auth.AccessToken, nil
+// NewTokenAuth prepares an auth with the token returned by an authorization
+// request, and client credentials for refreshing when it expires.
+func NewTokenAuth(clientID, clientSecret string, token *mondodomain.Token) *UserAuth {
+	auth := &UserAuth{ClientID: clientID, ClientSecret: clientSecret}
+	auth.setToken(token)
+	return auth
+}
+
+// Persist saves the auth's current token to the store, replacing any token
+// already there, and uses the store from then on.
+func (auth *UserAuth) Persist(store TokenStore) error {
+	auth.Lock.Lock()
+	defer auth.Lock.Unlock()
+	auth.Store = store
+	return auth.persist()
+}
+
+// persist saves the current token to the Store, replacing any token already
+// there. Requires the lock.
+func (auth *UserAuth) persist() error {
+	for {
+		stored, err := auth.Store.Load()
+		if err != nil {
+			return WrapError(err, "Failed to load stored token", nil, nil)
+		}
+		auth.stored = stored
+		if err := auth.save(); !errors.Is(err, ErrTokenConflict) {
+			return err
+		}
+	}
+}
+
+// adopt takes the token of another auth for the same user, with the given
+// OnEvent, and saves it to the Store. Requires the lock not be held.
+func (auth *UserAuth) adopt(other *UserAuth, onEvent func(AuthEvent)) error {
+	other.Lock.RLock()
+	accessToken, refreshToken := other.AccessToken, other.RefreshToken
+	expiry, lifetime := other.Expiry, other.lifetime
+	other.Lock.RUnlock()
+
+	auth.Lock.Lock()
+	defer auth.unlock()
+	auth.OnEvent = onEvent
+	auth.AccessToken, auth.RefreshToken = accessToken, refreshToken
+	auth.Expiry, auth.lifetime = expiry, lifetime
+	auth.reauthErr = nil
+	return auth.persist()
+}
+
+// Revoke logs the user out through the API, revoking the access and refresh
+// tokens, then clears them from memory and the Store so that subsequent calls
+// to Get return ErrNoCredentials. An access token rejected by the API is
+// refreshed and the logout retried once. Should the logout fail, the tokens
+// are kept so that it can be retried, and its error is returned. A token saved
+// to the Store by another process since it was last loaded isn't the one
+// revoked, so it is kept and ErrTokenConflict returned.
+func (auth *UserAuth) Revoke(ctx context.Context, client *Client) error {
+	err := auth.logout(ctx, client, false)
+	if errors.Is(err, ErrInvalidToken) {
+		err = auth.logout(ctx, client, true)
+	}
+	// Without credentials there is no grant left for us to revoke.
+	if err != nil && err != ErrNoCredentials && !IsReauthorizationRequired(err) {
+		return err
+	}
+
+	auth.Lock.Lock()
+	defer auth.unlock()
+	auth.AccessToken = ""
+	auth.RefreshToken = ""
+	auth.Expiry = time.Time{}
+	auth.reauthErr = nil
+	auth.record(AuthRevoked, nil, false)
+	return auth.clearStore()
+}
+
+// logout revokes a current access token through the API, bypassing the
+// Client's Auth in case it isn't this one.
+func (auth *UserAuth) logout(ctx context.Context, client *Client, invalidate bool) error {
+	token, err := auth.GetContext(ctx, invalidate, client)
+	if err != nil {
+		return err
+	}
+	unauthed := *client
+	unauthed.Auth = nil
+	return unauthed.DoIntoContext(ctx, mondohttp.NewLogoutRequest(token), &struct{}{})
+}
+
+// clearStore deletes the token last loaded or saved from the Store. Requires
+// the lock.
+func (auth *UserAuth) clearStore() error {
+	if auth.Store == nil {
+		return nil
+	}
+	if err := auth.Store.CompareAndSwap(auth.stored, nil); err != nil {
+		return WrapError(err, "Failed to delete stored token", nil, nil)
+	}
+	auth.stored = nil
+	return nil
 }
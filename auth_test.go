@@ -0,0 +1,239 @@
+package mondo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondotest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserAuth_ProactiveRefresh(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	auth.Expiry = time.Now().Add(30 * time.Second)
+
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"POST /oauth2/token", "GET /accounts", "GET /accounts"}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+	if until := time.Until(auth.Expiry); until < 5*time.Hour || until > 6*time.Hour {
+		t.Fatalf("Expected the new token to expire in 6 hours but got %s", until)
+	}
+}
+
+func TestUserAuth_ExpiringFallback(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	auth.Expiry = time.Now().Add(30 * time.Second)
+
+	// The refresh fails, but the current token hasn't yet expired.
+	s.Fail("/oauth2/token", mondotest.ServerError, 1)
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"POST /oauth2/token", "GET /accounts"}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+}
+
+func TestUserAuth_KeepFresh(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+
+	// The token's expiry is unknown, so it's refreshed immediately. The new
+	// token lasts 6 hours, so the skew is limited to 3 hours, and the next
+	// refresh isn't due until then.
+	auth.RefreshSkew = 7 * time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- auth.KeepFresh(ctx, client) }()
+
+	for len(s.Requests()) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected KeepFresh to be cancelled but got %v", err)
+	}
+
+	expected := []string{"POST /oauth2/token"}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+
+	// Nor do requests refresh the token.
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, "GET /accounts")
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+}
+
+func TestUserAuth_RefreshInterval(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	auth.Expiry = time.Now().Add(30 * time.Second)
+
+	var events []mondo.AuthEvent
+	auth.OnEvent = func(event mondo.AuthEvent) {
+		events = append(events, event)
+	}
+
+	// Failed refreshes of the expiring token are reported, and not retried by
+	// every request.
+	s.Fail("/oauth2/token", mondotest.ServerError, 3)
+	for i := 0; i < 3; i++ {
+		if _, err := client.Accounts(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"POST /oauth2/token", "GET /accounts", "GET /accounts", "GET /accounts"}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+	if len(events) != 1 || events[0].Type != mondo.AuthRefreshFailed || events[0].Terminal {
+		t.Fatalf("Expected a non-terminal refresh failure but got %#v", events)
+	}
+}
+
+func TestUserAuth_Revoke(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	store := &mondo.MemoryTokenStore{}
+	if err := auth.Persist(store); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := auth.Revoke(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	if token := s.Token(); token.AccessToken != "" || token.RefreshToken != "" {
+		t.Fatalf("Expected the API's tokens to be revoked but got %#v", token)
+	}
+	if stored, _ := store.Load(); stored != nil {
+		t.Fatalf("Expected the stored token to be deleted but got %#v", stored)
+	}
+	if _, err := auth.Get(false, client); err != mondo.ErrNoCredentials {
+		t.Fatalf("Expected ErrNoCredentials but got %v", err)
+	}
+}
+
+func TestUserAuth_RevokeExpired(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+
+	// The expired access token is refreshed to log out.
+	s.ExpireToken()
+	if err := auth.Revoke(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	if token := s.Token(); token.AccessToken != "" || token.RefreshToken != "" {
+		t.Fatalf("Expected the API's tokens to be revoked but got %#v", token)
+	}
+	expected := []string{"POST /oauth2/logout", "POST /oauth2/token", "POST /oauth2/logout"}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+}
+
+func TestUserAuth_RevokeFailed(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	store := &mondo.MemoryTokenStore{}
+	if err := auth.Persist(store); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tokens are kept for retrying when the logout fails.
+	s.Fail("/oauth2/logout", mondotest.ServerError, 1)
+	if err := auth.Revoke(context.Background(), client); !errors.Is(err, mondo.ErrServerError) {
+		t.Fatalf("Expected ErrServerError but got %v", err)
+	}
+	if stored, _ := store.Load(); stored == nil || auth.RefreshToken == "" {
+		t.Fatal("Expected the tokens to be kept")
+	}
+
+	if err := auth.Revoke(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	if token := s.Token(); token.RefreshToken != "" {
+		t.Fatalf("Expected the API's tokens to be revoked but got %#v", token)
+	}
+}
+
+func TestUserAuth_ReauthorizationRequired(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	auth.ClientSecret = "rotated"
+
+	var events []mondo.AuthEvent
+	auth.OnEvent = func(event mondo.AuthEvent) {
+		// Events are reported outside of the lock.
+		auth.Lock.RLock()
+		auth.Lock.RUnlock()
+		events = append(events, event)
+	}
+
+	// Transient failures, including those of 401s which don't reject the
+	// credentials, don't prevent the next refresh.
+	s.ExpireToken()
+	for _, failure := range []mondotest.Failure{mondotest.ServerError, mondotest.ExpiredToken} {
+		s.Fail("/oauth2/token", failure, 1)
+		if _, err := client.Accounts(context.Background()); err == nil || mondo.IsReauthorizationRequired(err) {
+			t.Fatalf("Expected a transient error but got %v", err)
+		}
+	}
+
+	// The rejected client secret requires reauthorization.
+	for i := 0; i < 2; i++ {
+		if _, err := client.Accounts(context.Background()); !mondo.IsReauthorizationRequired(err) {
+			t.Fatalf("Expected a ReauthorizationError but got %v", err)
+		}
+	}
+
+	tokenRequests := 0
+	for _, request := range s.Requests() {
+		if request == "POST /oauth2/token" {
+			tokenRequests++
+		}
+	}
+	if tokenRequests != 3 {
+		t.Fatalf("Expected 3 token requests but got %d", tokenRequests)
+	}
+
+	if len(events) != 3 || events[0].Type != mondo.AuthRefreshFailed || events[0].Terminal || events[1].Terminal || !events[2].Terminal {
+		t.Fatalf("Expected transient then terminal refresh_failed events but got %#v", events)
+	}
+}
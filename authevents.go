@@ -0,0 +1,97 @@
+package mondo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AuthEventType identifies a change in a UserAuth's credentials.
+type AuthEventType string
+
+// AuthEventTypes reported to UserAuth.OnEvent.
+const (
+	AuthRefreshed     AuthEventType = "refreshed"
+	AuthRefreshFailed AuthEventType = "refresh_failed"
+	AuthRevoked       AuthEventType = "revoked"
+	AuthSaveFailed    AuthEventType = "save_failed"
+)
+
+// AuthEvent describes a change in a UserAuth's credentials.
+type AuthEvent struct {
+	Type AuthEventType
+	Time time.Time
+	// Err is the cause of refresh_failed and save_failed events.
+	Err error
+	// Terminal indicates a refresh_failed event after which the user must
+	// reauthorize the application.
+	Terminal bool
+}
+
+// ReauthorizationError is returned by UserAuth once a refresh has been
+// rejected in a way that retrying can't fix, e.g. because the refresh token
+// was revoked or the client secret changed. The user must authorize the
+// application again; until then no further refreshes are attempted.
+type ReauthorizationError struct {
+	cause error
+}
+
+func (err *ReauthorizationError) Error() string {
+	return fmt.Sprintf("mondo: Reauthorization required (caused by: %s)", err.cause)
+}
+
+// Cause returns the error with which the refresh was rejected.
+func (err *ReauthorizationError) Cause() error {
+	return err.cause
+}
+
+// Unwrap returns the error with which the refresh was rejected, for use by
+// errors.Is and errors.As.
+func (err *ReauthorizationError) Unwrap() error {
+	return err.cause
+}
+
+// IsReauthorizationRequired reports whether the error, or any error it wraps,
+// is a ReauthorizationError.
+func IsReauthorizationRequired(err error) bool {
+	var reauthErr *ReauthorizationError
+	return errors.As(err, &reauthErr)
+}
+
+// refreshRejected reports whether a refresh failed because the API rejected
+// the refresh token or client credentials, rather than for reasons which may
+// pass.
+func refreshRejected(err error) bool {
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	return respErr.ErrorType == "invalid_grant" || respErr.ErrorType == "invalid_client"
+}
+
+// record queues an event to be reported once the lock is released. Requires
+// the lock.
+func (auth *UserAuth) record(eventType AuthEventType, err error, terminal bool) {
+	if auth.OnEvent == nil {
+		return
+	}
+	auth.events = append(auth.events, AuthEvent{
+		Type:     eventType,
+		Time:     time.Now(),
+		Err:      err,
+		Terminal: terminal,
+	})
+}
+
+// unlock releases the lock, then reports the events recorded while it was
+// held, so that OnEvent may use the UserAuth.
+func (auth *UserAuth) unlock() {
+	events := auth.events
+	auth.events = nil
+	onEvent := auth.OnEvent
+	auth.Lock.Unlock()
+
+	for _, event := range events {
+		onEvent(event)
+	}
+}
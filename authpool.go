@@ -0,0 +1,211 @@
+package mondo
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoUser indicates a request made through a UserAuthPool whose context
+// doesn't name the user it's made for.
+var ErrNoUser = errors.New("mondo: No user in request context")
+
+// DefaultPoolSize is the default number of UserAuths held by a UserAuthPool.
+const DefaultPoolSize int = 1000
+
+type userContextKey struct{}
+
+// WithUser returns a context for making requests on behalf of the user, via a
+// Client whose Auth is a UserAuthPool.
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, userID)
+}
+
+// UserFromContext returns the user given to WithUser.
+func UserFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userContextKey{}).(string)
+	return userID, ok
+}
+
+// UserAuthPool provides access tokens for many users, each request choosing
+// its user with WithUser. Each user's UserAuth is loaded from their TokenStore
+// when first needed, and the least recently used are discarded once there are
+// more than Size of them. There is only ever one UserAuth per user, so that
+// their refreshes are serialized. Thread-safe.
+type UserAuthPool struct {
+	ClientID     string
+	ClientSecret string
+	// Stores returns the TokenStore of a user.
+	Stores func(userID string) TokenStore
+	// Size bounds the number of UserAuths held, defaulting to DefaultPoolSize.
+	// UserAuths in use are never discarded, so the bound may be exceeded.
+	Size int
+	// OnEvent, if set, is called with the AuthEvents of each user, after any
+	// OnEvent of the UserAuth given to Put.
+	OnEvent func(userID string, event AuthEvent)
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	recent  *list.List
+}
+
+// poolEntry is a UserAuth held by a UserAuthPool, with the number of requests
+// currently using it.
+type poolEntry struct {
+	userID string
+	auth   *UserAuth
+	refs   int
+}
+
+// NewUserAuthPool prepares a pool whose users' tokens are in the stores
+// returned by the stores function, and refreshed with the client credentials.
+func NewUserAuthPool(clientID, clientSecret string, stores func(userID string) TokenStore) *UserAuthPool {
+	return &UserAuthPool{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Stores:       stores,
+	}
+}
+
+// Get satisfies the Client's Auth, but always fails with ErrNoUser as it has
+// no context to find the user in. Use the Client's context-aware methods.
+func (p *UserAuthPool) Get(invalidate bool, client *Client) (string, error) {
+	return p.GetContext(context.Background(), invalidate, client)
+}
+
+// GetContext returns an access token for the user named by the context.
+func (p *UserAuthPool) GetContext(ctx context.Context, invalidate bool, client *Client) (string, error) {
+	userID, ok := UserFromContext(ctx)
+	if !ok {
+		return "", ErrNoUser
+	}
+
+	entry := p.acquire(userID, nil)
+	defer p.release(entry)
+	return entry.auth.GetContext(ctx, invalidate, client)
+}
+
+// Put adds a newly authorized user's UserAuth to the pool, saving its token to
+// their TokenStore. Should the pool already hold a UserAuth for the user, it
+// is kept and takes the new token and OnEvent instead, so that there remains
+// only one UserAuth per user and TokenStore.
+func (p *UserAuthPool) Put(userID string, auth *UserAuth) error {
+	if entry := p.acquireExisting(userID); entry != nil {
+		defer p.release(entry)
+		return entry.auth.adopt(auth, p.onEvent(userID, auth.OnEvent))
+	}
+
+	store := p.Stores(userID)
+	if err := auth.Persist(store); err != nil {
+		return err
+	}
+	entry := p.acquire(userID, auth)
+	defer p.release(entry)
+	if entry.auth != auth {
+		// The user was loaded by a request in the meantime, so hand the token
+		// over to their UserAuth and leave the store to it.
+		auth.Lock.Lock()
+		auth.Store = nil
+		auth.Lock.Unlock()
+		return entry.auth.adopt(auth, p.onEvent(userID, auth.OnEvent))
+	}
+	return nil
+}
+
+// acquireExisting returns the user's entry, marked as in use, or nil if the
+// pool doesn't hold one.
+func (p *UserAuthPool) acquireExisting(userID string) *poolEntry {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	elem, ok := p.entries[userID]
+	if !ok {
+		return nil
+	}
+	p.recent.MoveToFront(elem)
+	entry := elem.Value.(*poolEntry)
+	entry.refs++
+	return entry
+}
+
+// acquire returns the user's entry, marked as in use, creating it with the
+// given auth or one loaded from the user's TokenStore as needed. An existing
+// entry keeps its auth.
+func (p *UserAuthPool) acquire(userID string, auth *UserAuth) *poolEntry {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.entries == nil {
+		p.entries = make(map[string]*list.Element)
+		p.recent = list.New()
+	}
+
+	elem, ok := p.entries[userID]
+	if ok {
+		p.recent.MoveToFront(elem)
+	} else {
+		if auth == nil {
+			auth = NewStoredAuth(p.ClientID, p.ClientSecret, p.Stores(userID))
+		}
+		auth.Lock.Lock()
+		auth.OnEvent = p.onEvent(userID, auth.OnEvent)
+		auth.Lock.Unlock()
+		elem = p.recent.PushFront(&poolEntry{userID: userID, auth: auth})
+		p.entries[userID] = elem
+		p.evict()
+	}
+
+	entry := elem.Value.(*poolEntry)
+	entry.refs++
+	return entry
+}
+
+// onEvent returns the OnEvent of a user's UserAuth, calling the given handler,
+// if any, then the pool's.
+func (p *UserAuthPool) onEvent(userID string, handler func(AuthEvent)) func(AuthEvent) {
+	poolHandler := p.OnEvent
+	if poolHandler == nil {
+		return handler
+	}
+	return func(event AuthEvent) {
+		if handler != nil {
+			handler(event)
+		}
+		poolHandler(userID, event)
+	}
+}
+
+// release marks an entry as no longer in use by the caller.
+func (p *UserAuthPool) release(entry *poolEntry) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	entry.refs--
+	p.evict()
+}
+
+// evict discards the least recently used entries not in use until the pool is
+// within its size. Requires the lock.
+func (p *UserAuthPool) evict() {
+	size := p.Size
+	if size <= 0 {
+		size = DefaultPoolSize
+	}
+	for elem := p.recent.Back(); elem != nil && p.recent.Len() > size; {
+		prev := elem.Prev()
+		if entry := elem.Value.(*poolEntry); entry.refs == 0 {
+			p.recent.Remove(elem)
+			delete(p.entries, entry.userID)
+		}
+		elem = prev
+	}
+}
+
+// Len returns the number of UserAuths held in the pool.
+func (p *UserAuthPool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.recent == nil {
+		return 0
+	}
+	return p.recent.Len()
+}
@@ -0,0 +1,132 @@
+package mondo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondotest"
+	"reflect"
+	"testing"
+)
+
+func TestUserAuthPool(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	token := s.Token()
+
+	stores := map[string]*mondo.MemoryTokenStore{}
+	for _, userID := range []string{"user_a", "user_b"} {
+		stores[userID] = &mondo.MemoryTokenStore{}
+		stores[userID].CompareAndSwap(nil, &mondo.StoredToken{
+			AccessToken:  "Bearer " + token.AccessToken,
+			RefreshToken: token.RefreshToken,
+		})
+	}
+	pool := mondo.NewUserAuthPool(mondotest.ClientID, mondotest.ClientSecret, func(userID string) mondo.TokenStore {
+		return stores[userID]
+	})
+	pool.Size = 1
+	client := &mondo.Client{HTTPClient: s.HTTPClient(), Auth: pool}
+
+	_, err := client.Accounts(context.Background())
+	if !errors.Is(err, mondo.ErrNoUser) {
+		t.Fatalf("Expected ErrNoUser but got %v", err)
+	}
+
+	for _, userID := range []string{"user_a", "user_b"} {
+		if _, err := client.Accounts(mondo.WithUser(context.Background(), userID)); err != nil {
+			t.Fatalf("%s: %s", userID, err)
+		}
+	}
+	if n := pool.Len(); n != 1 {
+		t.Fatalf("Expected the pool to hold 1 auth but got %d", n)
+	}
+
+	// Refreshed tokens are saved to the user's own store.
+	s.ExpireToken()
+	if _, err := client.Accounts(mondo.WithUser(context.Background(), "user_a")); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := stores["user_a"].Load()
+	if stored.AccessToken != "Bearer "+s.Token().AccessToken {
+		t.Fatalf("Expected user_a's refreshed token to be stored but got %#v", stored)
+	}
+	if stored, _ := stores["user_b"].Load(); stored.AccessToken != "Bearer "+token.AccessToken {
+		t.Fatalf("Expected user_b's token to be unchanged but got %#v", stored)
+	}
+}
+
+func TestUserAuthPool_PutExisting(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	token := s.Token()
+
+	store := &mondo.MemoryTokenStore{}
+	store.CompareAndSwap(nil, &mondo.StoredToken{
+		AccessToken:  "Bearer " + token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	})
+	pool := mondo.NewUserAuthPool(mondotest.ClientID, mondotest.ClientSecret, func(string) mondo.TokenStore {
+		return store
+	})
+	ctx := mondo.WithUser(context.Background(), "user_a")
+	if _, err := pool.GetContext(ctx, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reauthorizing a user held by the pool updates their existing auth,
+	// rather than replacing it with the one given.
+	auth := mondo.NewClientAccessTokenAuth(mondotest.ClientID, mondotest.ClientSecret, "Bearer reauthorized", "refresh")
+	if err := pool.Put("user_a", auth); err != nil {
+		t.Fatal(err)
+	}
+	if auth.Store != nil {
+		t.Fatal("Expected only the pool's auth to use the store")
+	}
+	auth.AccessToken = "Bearer unused"
+	if accessToken, err := pool.GetContext(ctx, false, nil); err != nil || accessToken != "Bearer reauthorized" {
+		t.Fatalf("Expected the reauthorized token but got %q, %v", accessToken, err)
+	}
+	if n := pool.Len(); n != 1 {
+		t.Fatalf("Expected the pool to hold 1 auth but got %d", n)
+	}
+}
+
+func TestUserAuthPool_OnEvent(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	var events []string
+	store := &mondo.MemoryTokenStore{}
+	pool := mondo.NewUserAuthPool(mondotest.ClientID, mondotest.ClientSecret, func(string) mondo.TokenStore {
+		return store
+	})
+	pool.OnEvent = func(userID string, event mondo.AuthEvent) {
+		events = append(events, "pool "+userID+" "+string(event.Type))
+	}
+	client := &mondo.Client{HTTPClient: s.HTTPClient(), Auth: pool}
+	ctx := mondo.WithUser(context.Background(), "user_a")
+
+	// Both the pool's and the UserAuth's handlers are called, whether Put
+	// adds the user or updates the UserAuth held for them.
+	for _, name := range []string{"first", "second"} {
+		token := s.Token()
+		auth := mondo.NewClientAccessTokenAuth(mondotest.ClientID, mondotest.ClientSecret, "Bearer "+token.AccessToken, token.RefreshToken)
+		name := name
+		auth.OnEvent = func(event mondo.AuthEvent) {
+			events = append(events, name+" "+string(event.Type))
+		}
+		if err := pool.Put("user_a", auth); err != nil {
+			t.Fatal(err)
+		}
+		s.ExpireToken()
+		if _, err := client.Accounts(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"first refreshed", "pool user_a refreshed", "second refreshed", "pool user_a refreshed"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Expected events %q but got %q", expected, events)
+	}
+}
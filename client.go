@@ -1,6 +1,7 @@
 package mondo
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -14,16 +15,38 @@ type auth interface {
 	Get(invalidate bool, client *Client) (string, error)
 }
 
+// contextAuth is implemented by auths whose token requests can be cancelled.
+type contextAuth interface {
+	GetContext(ctx context.Context, invalidate bool, client *Client) (string, error)
+}
+
 // Client wraps Mondo-specific error-handling and authentication around an HTTP
-// Client.
+// Client. Failed requests are retried according to Retry, if set, and each
+// attempt waits on Limiter, if set, before passing through the middleware
+// added with Use.
+//
+// UploadClient makes attachment uploads to the URLs given by the API, in place
+// of HTTPClient and its overrides, and defaults to http.DefaultClient.
 type Client struct {
-	HTTPClient httpclient
-	Auth       auth
+	HTTPClient   httpclient
+	Auth         auth
+	Retry        RetryPolicy
+	Limiter      *RateLimiter
+	UploadClient httpclient
+
+	middleware []Middleware
 }
 
 // Do performs a request and returns the raw HTTP response. Any authorization
 // headers on the request are overridden by what the Client's Auth provides.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.DoContext(req.Context(), req)
+}
+
+// DoContext performs a request as Do, bound to the given context. Cancelling
+// the context aborts the request and any token refresh it is waiting on.
+func (c *Client) DoContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.WithContext(ctx)
 	_, authedReq := req.Header[http.CanonicalHeaderKey("Authorization")]
 	if !authedReq || c.Auth == nil {
 		return c.do(req)
@@ -35,14 +58,20 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 // authorization headers on the request are overridden by what the Client's
 // Auth provides.
 func (c *Client) DoInto(req *http.Request, target interface{}) error {
-	resp, err := c.Do(req)
+	return c.DoIntoContext(req.Context(), req, target)
+}
+
+// DoIntoContext performs a request as DoInto, bound to the given context.
+func (c *Client) DoIntoContext(ctx context.Context, req *http.Request, target interface{}) error {
+	resp, err := c.DoContext(ctx, req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return WrapError(err, "Failed to read response body", req, resp)
+		return wrapContextError(err, "Failed to read response body", req, resp)
 	}
 
 	err = json.Unmarshal(body, target)
@@ -54,46 +83,77 @@ func (c *Client) DoInto(req *http.Request, target interface{}) error {
 }
 
 func (c *Client) do(req *http.Request) (*http.Response, error) {
-	resp, err := c.HTTPClient.Do(req)
+	for attempts := 1; ; attempts++ {
+		resp, err := c.attempt(req)
+		if err == nil || c.Retry == nil {
+			return resp, err
+		}
+
+		wait, retry := c.Retry.Retry(attempts, req, resp, err)
+		if !retry || rewindBody(req) != nil {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if sleepErr := sleep(req.Context(), wait); sleepErr != nil {
+			return nil, WrapError(sleepErr, "Request cancelled", req, nil)
+		}
+	}
+}
+
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	if c.Limiter != nil {
+		err := c.Limiter.Wait(req.Context(), req.Header.Get("Authorization"))
+		if err != nil {
+			return nil, WrapError(err, "Request cancelled", req, nil)
+		}
+	}
+
+	resp, err := c.send(req)
 	if err != nil {
-		return resp, WrapError(err, "HTTP request failed", req, resp)
+		return resp, wrapContextError(err, "HTTP request failed", req, resp)
 	}
 
 	if resp.StatusCode != 200 {
-		respErr := DecodeError(req, resp)
-		if _, ok := respErr.(*ResponseError); ok {
-			return resp, respErr
-		}
-		return resp, WrapError(respErr, "Non-200 response returned and an error occured during parsing", req, resp)
+		return resp, DecodeError(req, resp)
 	}
 
 	return resp, nil
 }
 
 func (c *Client) doAuth(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var _err error
-
-	for attempt := 0; attempt < 2; attempt++ {
+	for attempt := 0; ; attempt++ {
 		// Get the token. Get a fresh token in subsequent attempts.
 		invalidate := attempt > 0
-		token, err := c.Auth.Get(invalidate, c)
+		token, err := c.getToken(req.Context(), invalidate)
 		if err != nil {
-			return nil, WrapError(err, "Failed to get authentication details", req, nil)
+			return nil, wrapContextError(err, "Failed to get authentication details", req, nil)
 		}
 
 		req.Header.Set("Authorization", token)
-		resp, err = c.do(req)
-		if err != nil {
-			if mondoErr, ok := err.(*ResponseError); ok && mondoErr.InvalidToken {
-				_err = mondoErr
+		resp, err := c.do(req)
+
+		// Retry once with a fresh token if the API rejected ours, replaying
+		// the body of the request where it has one.
+		if mondoErr, ok := err.(*ResponseError); ok && mondoErr.InvalidToken && attempt == 0 {
+			if rewindBody(req) == nil {
+				resp.Body.Close()
 				continue
 			}
-			return resp, err
 		}
-
-		break
+		return resp, err
 	}
+}
 
-	return resp, _err
+// getToken asks the Client's Auth for a token, passing the context along if
+// the Auth supports it.
+func (c *Client) getToken(ctx context.Context, invalidate bool) (string, error) {
+	if auth, ok := c.Auth.(contextAuth); ok {
+		return auth.GetContext(ctx, invalidate, c)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.Auth.Get(invalidate, c)
 }
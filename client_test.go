@@ -0,0 +1,72 @@
+package mondo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondohttp"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_DoContextCancelled(t *testing.T) {
+	attempts := 0
+	client := &mondo.Client{
+		HTTPClient: mondo.DoFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return &http.Response{
+					StatusCode: 503,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader(`{"message": "Unavailable"}`)),
+					Request:    req,
+				}, nil
+			}
+			// Block until the request is abandoned, as would a slow server.
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}),
+		Auth:  mondo.NewAccessTokenAuth("secret_token"),
+		Retry: &mondo.ExponentialBackoff{BaseDelay: time.Hour},
+	}
+	newRequest := func() *http.Request {
+		return mondohttp.NewTransactionsRequest("", "acc_secret", false, "", "", 0)
+	}
+
+	tests := []struct {
+		name     string
+		attempts int
+		timeout  time.Duration
+		expected error
+	}{
+		// Cancelled while waiting to retry the 503.
+		{"retry wait", 0, 50 * time.Millisecond, context.DeadlineExceeded},
+		// Cancelled while the request is in flight.
+		{"in flight", 1, 0, context.Canceled},
+	}
+	for _, test := range tests {
+		attempts = test.attempts
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.timeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), test.timeout)
+		} else {
+			time.AfterFunc(50*time.Millisecond, cancel)
+		}
+
+		err := client.DoIntoContext(ctx, newRequest(), &struct{}{})
+		cancel()
+		if !errors.Is(err, test.expected) {
+			t.Fatalf("%s: expected %v but got %v", test.name, test.expected, err)
+		}
+		var wrapped *mondo.Error
+		if !errors.As(err, &wrapped) || wrapped.Request == nil {
+			t.Fatalf("%s: expected an Error with the request but got %#v", test.name, err)
+		}
+		if msg := err.Error(); strings.Contains(msg, "acc_secret") || strings.Contains(msg, "secret_token") || !strings.Contains(msg, mondo.Redacted) {
+			t.Fatalf("%s: expected the request to be redacted but got %s", test.name, msg)
+		}
+	}
+}
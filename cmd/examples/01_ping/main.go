@@ -2,11 +2,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/icio/mondo"
 	"github.com/icio/mondo/mondodomain"
 	"github.com/icio/mondo/mondohttp"
-	"github.com/pkg/errors"
 	"io/ioutil"
 	"net/http"
 )
@@ -51,7 +51,9 @@ func main() {
 	// means of authentication.
 	_, err = client.Do(mondohttp.NewAccountsRequest("Bearer abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzab"))
 	fmt.Printf("Error: %s\n", err)
-	if mondoErr, ok := errors.Cause(err).(*mondo.ResponseError); ok {
+	var mondoErr *mondo.ResponseError
+	if errors.As(err, &mondoErr) {
 		fmt.Println("Invalid token:", mondoErr.InvalidToken)
 	}
+	fmt.Println("Unauthorized:", errors.Is(err, mondo.ErrUnauthorized))
 }
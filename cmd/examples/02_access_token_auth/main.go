@@ -11,9 +11,8 @@
 package main
 
 import (
+	"context"
 	"github.com/icio/mondo"
-	"github.com/icio/mondo/mondodomain"
-	"github.com/icio/mondo/mondohttp"
 	"log"
 	"net/http"
 	"os"
@@ -34,10 +33,9 @@ func main() {
 		Auth: mondo.NewAccessTokenAuth(os.Getenv("MONDO_ACCESS_TOKEN")),
 	}
 
-	// mondo.Client is going to override the Authorization header, so we just
-	// provide an empty string for the access token when creating the request.
-	accounts := new(mondodomain.AccountCollection)
-	err := client.DoInto(mondohttp.NewAccountsRequest(""), accounts)
+	// mondo.Client's typed methods build the request and decode the response
+	// for us, leaving the Authorization header to the client's auth.
+	accounts, err := client.Accounts(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
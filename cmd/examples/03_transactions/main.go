@@ -4,11 +4,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/icio/mondo"
-	"github.com/icio/mondo/mondodomain"
-	"github.com/icio/mondo/mondohttp"
 	"log"
 	"net/http"
 	"os"
@@ -25,40 +24,27 @@ func main() {
 	}
 
 	// Get the first account.
-	accounts := new(mondodomain.AccountCollection)
-	err := client.DoInto(mondohttp.NewAccountsRequest(""), accounts)
+	accounts, err := client.Accounts(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
-	account := accounts.Accounts[0].ID
+	account := accounts[0].ID
 
-	// The trans channel will recieve all of the transactions from the account
-	// until all transactions have been listed, or we signal to stop.
-	trans := make(chan mondodomain.Transaction)
+	// Iterate through the account's transactions from the beginning of its
+	// history, loading 30 per page from the API. Pages are only requested as
+	// we advance, so we can simply stop ranging when we have enough.
+	trans := client.Transactions(context.Background(), mondo.TransactionsOptions{
+		AccountID:       account,
+		ExpandMerchants: true,
+		PageSize:        30,
+	})
 
-	// We can use the stop channel to signal we want no more transactions. (If
-	// our application always iterates through all transactions we can pass nil
-	// into IterTransactions.)
-	stop := make(chan bool)
-
-	// The iteration is done from a goroutine we create ourselves:
-	// mondo.IterTransactions will block until it has written all transactions
-	// from the API to the channel we give it, or until it receives a stop
-	// signal.
-	go func() {
-		defer close(trans)
-		defer close(stop)
-
-		// Load 30 transactions per page from the API, from the beginning of
-		// the account's history.
-		err := client.IterTransactions(trans, stop, "", account, true, "", "", 30)
+	n := 0
+	for tran, err := range trans.All() {
 		if err != nil {
 			log.Fatal(err)
 		}
-	}()
 
-	n := 0
-	for tran := range trans {
 		// Dump the transaction.
 		enc, err := json.Marshal(tran)
 		if err != nil {
@@ -69,7 +55,7 @@ func main() {
 		// Stop after the first 120 results.
 		n++
 		if n == 120 {
-			stop <- true
+			break
 		}
 	}
 }
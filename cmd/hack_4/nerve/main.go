@@ -85,12 +85,12 @@ func journeys(m *mondo.Client, w http.ResponseWriter, r *http.Request) {
 
 	// Merge the time-ordered list of journeys
 	added := 0
-	spent := float64(0)
+	spent := mondodomain.Money{Currency: "GBP"}
 
 	allSightings, added = mergeSightings(allSightings, sightings)
 	if added > 0 {
 		lastSeen := allSightings[len(allSightings)-1]
-		spent = float64(lastSeen.Cost - allCost)
+		spent.Amount = int64(lastSeen.Cost - allCost)
 		allCost = lastSeen.Cost
 
 		if lastSeen.Out {
@@ -106,7 +106,7 @@ func journeys(m *mondo.Client, w http.ResponseWriter, r *http.Request) {
 				"",
 				os.Getenv("MONDO_ACCOUNT_ID"),
 				"http://www.nyan.cat/",
-				fmt.Sprintf("Welcome to %s. This journey cost you £%.2f.%s", lastSeen.Place, spent/100, suffix),
+				fmt.Sprintf("Welcome to %s. This journey cost you %s.%s", lastSeen.Place, spent, suffix),
 				"https://tfl.gov.uk/cdn/static/assets/icons/favicon-160x160.png",
 			))
 			if err != nil {
@@ -207,15 +207,14 @@ func extrapolateSightings(acc *hack_4.Account) []hack_4.Sighting {
 // accounts writes the list of a user's mondo accounts in the response.
 func accounts(m *mondo.Client, w http.ResponseWriter, r *http.Request) {
 	// Get the accounts listing.
-	accounts := new(mondodomain.AccountsResponse)
-	err := m.DoInto(mondohttp.NewAccountsRequest(""), accounts)
+	accounts, err := m.Accounts(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Send the response to the client.
-	body, err := json.Marshal(accounts.Accounts)
+	body, err := json.Marshal(accounts)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(body)
 }
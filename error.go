@@ -1,15 +1,32 @@
 package mondo
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // InvalidToken is the Mondo error type representing an expired access token.
 const InvalidToken string = "invalid_token"
 
+// Errors which ResponseErrors match with errors.Is, according to their status.
+var (
+	ErrBadRequest   = errors.New("mondo: Bad request")
+	ErrUnauthorized = errors.New("mondo: Unauthorized")
+	ErrForbidden    = errors.New("mondo: Forbidden")
+	ErrNotFound     = errors.New("mondo: Not found")
+	ErrRateLimited  = errors.New("mondo: Rate limited")
+	ErrServerError  = errors.New("mondo: Server error")
+	// ErrInvalidToken is matched by ResponseErrors whose InvalidToken is set.
+	ErrInvalidToken = errors.New("mondo: Invalid token")
+)
+
 // Error is a generic library error with additional details about the context.
 type Error struct {
 	Request  *http.Request
@@ -28,11 +45,31 @@ func WrapError(cause error, message string, req *http.Request, resp *http.Respon
 	}
 }
 
+// wrapContextError creates a library Error as WrapError, but reports the
+// request's context error as the cause when the context is done.
+func wrapContextError(cause error, message string, req *http.Request, resp *http.Response) *Error {
+	if req != nil && req.Context().Err() != nil {
+		return WrapError(req.Context().Err(), "Request cancelled", req, resp)
+	}
+	return WrapError(cause, message, req, resp)
+}
+
 func (err *Error) Error() string {
 	return fmt.Sprintf(
 		"mondo: %s (caused by: %s) during {%s}",
 		err.Message,
-		err.cause,
+		redactError(err.cause),
+		formatReqResp(err.Request, err.Response),
+	)
+}
+
+// GoString formats the Error for %#v without dumping its Request and Response,
+// whose headers include credentials.
+func (err *Error) GoString() string {
+	return fmt.Sprintf(
+		"&mondo.Error{Message:%q, Cause:%q, During:%q}",
+		err.Message,
+		fmt.Sprint(redactError(err.cause)),
 		formatReqResp(err.Request, err.Response),
 	)
 }
@@ -44,6 +81,11 @@ func (err *Error) Cause() error {
 	return err.cause
 }
 
+// Unwrap returns the cause of the Error, for use by errors.Is and errors.As.
+func (err *Error) Unwrap() error {
+	return err.cause
+}
+
 // ResponseError respresents error messages successfully returned from Mondo.
 type ResponseError struct {
 	Request  *http.Request
@@ -58,33 +100,133 @@ type ResponseError struct {
 
 	// InvalidToken usually indicates whether a token has expired.
 	InvalidToken bool
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// RetryAfter is how long the response asked us to wait before retrying,
+	// typically given with 429 responses.
+	RetryAfter time.Duration
+	// Body is the start of the response body, when it couldn't be decoded as
+	// an API error.
+	Body string
 }
 
-// DecodeError parses ResponseErrors from Mondo API calls.
+// maxErrorBodyBytes caps how much of an error response body is read.
+const maxErrorBodyBytes = 64 << 10
+
+// errorSnippetBytes caps how much of an undecodable error response body is
+// kept in ResponseError.Body.
+const errorSnippetBytes = 512
+
+// DecodeError parses ResponseErrors from Mondo API calls, closing the response
+// body. Responses which aren't the API's JSON errors, such as the HTML or empty
+// bodies of proxies, are described by their status line and a snippet of their
+// body, so a ResponseError is always returned.
 func DecodeError(req *http.Request, resp *http.Response) error {
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return WrapError(err, "Failed to read response body", req, resp)
-	}
+	defer resp.Body.Close()
 
 	mondoErr := &ResponseError{
-		Request:  req,
-		Response: resp,
-		TraceID:  resp.Header.Get("Trace-ID"),
+		Request:    req,
+		Response:   resp,
+		TraceID:    resp.Header.Get("Trace-ID"),
+		StatusCode: resp.StatusCode,
+	}
+	mondoErr.RetryAfter, _ = retryAfter(resp)
+
+	// OAuth2 errors describe themselves with error_description in place of
+	// the API's message.
+	var oauthErr struct {
+		Description string `json:"error_description"`
 	}
-	err = json.Unmarshal(body, mondoErr)
-	if err != nil {
-		return WrapError(err, "Failed to decode error response", req, resp)
+	body := readErrorBody(resp)
+	decoded := json.Unmarshal(body, mondoErr) == nil && json.Unmarshal(body, &oauthErr) == nil
+	if mondoErr.Message == "" {
+		mondoErr.Message = oauthErr.Description
+	}
+	if !decoded || (mondoErr.Code == "" && mondoErr.ErrorType == "" && mondoErr.Message == "") {
+		mondoErr.Code = ""
+		mondoErr.ErrorType = ""
+		mondoErr.Params = nil
+		mondoErr.Message = resp.Status
+		if mondoErr.Message == "" {
+			mondoErr.Message = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		mondoErr.Body = errorSnippet(body)
 	}
 
 	mondoErr.InvalidToken = mondoErr.ErrorType == InvalidToken
 	return mondoErr
 }
 
+// readErrorBody reads up to maxErrorBodyBytes of the response body,
+// decompressing it if the transport hasn't. Read errors are ignored, leaving
+// whatever was read before them.
+func readErrorBody(resp *http.Response) []byte {
+	var body io.Reader = io.LimitReader(resp.Body, maxErrorBodyBytes)
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") && !resp.Uncompressed {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, maxErrorBodyBytes)
+	}
+	content, _ := ioutil.ReadAll(body)
+	return content
+}
+
+// errorSnippet returns the start of the body as printable text.
+func errorSnippet(body []byte) string {
+	truncated := len(body) > errorSnippetBytes
+	if truncated {
+		body = body[:errorSnippetBytes]
+	}
+	snippet := strings.TrimSpace(strings.ToValidUTF8(string(body), ""))
+	if truncated {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// Is reports whether the error matches one of the sentinel errors, such as
+// ErrNotFound, for use by errors.Is.
+func (err *ResponseError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return err.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return err.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return err.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return err.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return err.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return err.StatusCode >= 500
+	case ErrInvalidToken:
+		return err.InvalidToken
+	}
+	return false
+}
+
+// BadParam returns the name of the request parameter that a bad request
+// complained of, from error codes such as "bad_request.bad_param.limit" or
+// "bad_request.missing_param.account_id".
+func (err *ResponseError) BadParam() string {
+	for _, prefix := range []string{"bad_request.bad_param.", "bad_request.missing_param."} {
+		if strings.HasPrefix(err.Code, prefix) {
+			return err.Code[len(prefix):]
+		}
+	}
+	return ""
+}
+
 func (err *ResponseError) Error() string {
 	errType := ""
 	if err.ErrorType != "" {
 		errType = fmt.Sprintf(" (%s)", err.ErrorType)
+	} else if err.Body != "" {
+		errType = fmt.Sprintf(" (body: %q)", err.Body)
 	}
 	return fmt.Sprintf(
 		"mondo: %s%s during {%s} (trace: %s)",
@@ -95,6 +237,21 @@ func (err *ResponseError) Error() string {
 	)
 }
 
+// GoString formats the ResponseError for %#v without dumping its Request and
+// Response, whose headers include credentials.
+func (err *ResponseError) GoString() string {
+	return fmt.Sprintf(
+		"&mondo.ResponseError{StatusCode:%d, TraceID:%q, Code:%q, ErrorType:%q, Message:%q, Params:%#v, During:%q}",
+		err.StatusCode,
+		err.TraceID,
+		err.Code,
+		err.ErrorType,
+		err.Message,
+		err.Params,
+		formatReqResp(err.Request, err.Response),
+	)
+}
+
 func (err *Error) String() string {
 	return err.Error()
 }
@@ -116,8 +273,10 @@ func formatReqResp(req *http.Request, resp *http.Response) string {
 	return desc
 }
 
+// formatReq describes the request by its method and URL, redacting any
+// sensitive query parameters.
 func formatReq(req *http.Request) string {
-	return fmt.Sprintf("%s %s", req.Method, req.URL)
+	return fmt.Sprintf("%s %s", req.Method, RedactURL(req.URL))
 }
 
 func formatResp(resp *http.Response) string {
@@ -0,0 +1,160 @@
+package mondo_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondohttp"
+	"github.com/icio/mondo/mondotest"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseError_Is(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	ctx := context.Background()
+
+	_, err := client.Transaction(ctx, "tx_missing", false)
+	if !errors.Is(err, mondo.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound but got %v", err)
+	}
+	if errors.Is(err, mondo.ErrBadRequest) || errors.Is(err, mondo.ErrServerError) {
+		t.Fatalf("Expected only ErrNotFound to match %v", err)
+	}
+
+	s.Fail("/accounts", mondotest.ServerError, 1)
+	_, err = client.Accounts(ctx)
+	if !errors.Is(err, mondo.ErrServerError) {
+		t.Fatalf("Expected ErrServerError but got %v", err)
+	}
+
+	s.Fail("/accounts", mondotest.RateLimited, 1)
+	_, err = client.Accounts(ctx)
+	if !errors.Is(err, mondo.ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited but got %v", err)
+	}
+
+	err = client.DoIntoContext(ctx, mondohttp.NewTransactionsRequest("", "acc_00000001", false, "", "", 1000), &struct{}{})
+	var respErr *mondo.ResponseError
+	if !errors.As(err, &respErr) || !errors.Is(err, mondo.ErrBadRequest) {
+		t.Fatalf("Expected a bad request ResponseError but got %v", err)
+	}
+	if param := respErr.BadParam(); param != "limit" {
+		t.Fatalf("Expected bad param %q but got %q", "limit", param)
+	}
+}
+
+func TestDecodeError_RetryAfter(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Status:     "429 Too Many Requests",
+		Header:     http.Header{"Retry-After": {"2"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"code": "too_many_requests", "message": "Slow down"}`)),
+	}
+	err := mondo.DecodeError(nil, resp)
+
+	var respErr *mondo.ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("Expected a ResponseError but got %v", err)
+	}
+	if respErr.StatusCode != http.StatusTooManyRequests || respErr.RetryAfter != 2*time.Second {
+		t.Fatalf("Expected 429 with 2s Retry-After but got %d with %s", respErr.StatusCode, respErr.RetryAfter)
+	}
+	if !errors.Is(err, mondo.ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited to match %v", err)
+	}
+}
+
+func TestDecodeError_ErrorOnly(t *testing.T) {
+	tests := []struct {
+		body        string
+		wantType    string
+		wantMessage string
+	}{
+		{`{"error": "invalid_token"}`, "invalid_token", ""},
+		{`{"error": "invalid_grant", "error_description": "Refresh token revoked"}`, "invalid_grant", "Refresh token revoked"},
+	}
+	for _, test := range tests {
+		resp := &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Status:     "401 Unauthorized",
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(test.body)),
+		}
+		var respErr *mondo.ResponseError
+		if err := mondo.DecodeError(nil, resp); !errors.As(err, &respErr) {
+			t.Fatalf("Expected a ResponseError but got %v", err)
+		}
+		if respErr.ErrorType != test.wantType || respErr.Message != test.wantMessage || respErr.Body != "" {
+			t.Errorf("Expected type %q and message %q from %s but got %#v", test.wantType, test.wantMessage, test.body, respErr)
+		}
+		if respErr.InvalidToken != (test.wantType == mondo.InvalidToken) {
+			t.Errorf("Expected InvalidToken %t from %s", !respErr.InvalidToken, test.body)
+		}
+	}
+}
+
+func TestDecodeError_NonJSON(t *testing.T) {
+	gzipped := new(bytes.Buffer)
+	gz := gzip.NewWriter(gzipped)
+	gz.Write([]byte(`{"code": "internal_service", "message": "Compressed"}`))
+	gz.Close()
+
+	tests := []struct {
+		name        string
+		status      string
+		header      http.Header
+		body        string
+		wantMessage string
+		wantBody    string
+	}{
+		{"empty", "502 Bad Gateway", nil, "", "502 Bad Gateway", ""},
+		{"html", "503 Service Unavailable", nil, "<html>\n<h1>Down</h1></html>\n", "503 Service Unavailable", "<html>\n<h1>Down</h1></html>"},
+		{"truncated", "500 Internal Server Error", nil, strings.Repeat("x", 10000), "500 Internal Server Error", strings.Repeat("x", 512) + "..."},
+		{"gzip", "500 Internal Server Error", http.Header{"Content-Encoding": {"gzip"}}, gzipped.String(), "Compressed", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var code int
+			fmt.Sscan(test.status, &code)
+			body := &closeRecorder{Reader: strings.NewReader(test.body)}
+			resp := &http.Response{StatusCode: code, Status: test.status, Header: test.header, Body: body}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+
+			var respErr *mondo.ResponseError
+			if err := mondo.DecodeError(nil, resp); !errors.As(err, &respErr) {
+				t.Fatalf("Expected a ResponseError but got %v", err)
+			}
+			if respErr.Message != test.wantMessage || respErr.Body != test.wantBody {
+				t.Fatalf("Expected message %q and body %q but got %q and %q", test.wantMessage, test.wantBody, respErr.Message, respErr.Body)
+			}
+			if !errors.Is(respErr, mondo.ErrServerError) {
+				t.Fatalf("Expected ErrServerError to match %v", respErr)
+			}
+			if !body.closed {
+				t.Fatal("Expected the response body to be closed")
+			}
+		})
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
@@ -12,8 +12,12 @@ type HTTPClient struct {
 	UserAgent string
 }
 
-// Do performs the HTTP request as http.Client.Do, setting overrides.
+// Do performs the HTTP request as http.Client.Do, setting overrides. Requests
+// whose context is already done are not passed on to the wrapped Client.
 func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
 	if c.UserAgent == "" {
 		req.Header.Del("User-Agent")
 	} else {
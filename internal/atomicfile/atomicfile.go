@@ -0,0 +1,69 @@
+// Package atomicfile replaces files such that readers never see them
+// partially written.
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File is a temporary file which replaces the file at its path once
+// committed.
+type File struct {
+	*os.File
+	path string
+}
+
+// Create opens a temporary file in the same directory as path, with the given
+// permissions, to be written to and then committed or aborted.
+func Create(path string, perm os.FileMode) (*File, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &File{File: tmp, path: path}, nil
+}
+
+// Commit flushes the file to disk and renames it into place, leaving it open.
+func (f *File) Commit() error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), f.path)
+}
+
+// Abort closes and removes the temporary file, leaving the file at path as it
+// was. It mustn't be called once the file has been committed.
+func (f *File) Abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFile replaces the file at path with the data and permissions, by
+// writing to a temporary file in the same directory and renaming it into
+// place.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	f, err := Create(path, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Abort()
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Commit(); err != nil {
+		return err
+	}
+	return f.Close()
+}
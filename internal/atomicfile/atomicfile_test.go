@@ -0,0 +1,64 @@
+package atomicfile_test
+
+import (
+	"github.com/icio/mondo/internal/atomicfile"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+
+	for _, content := range []string{"first", "second"} {
+		if err := atomicfile.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := ioutil.ReadFile(path); err != nil || string(data) != content {
+			t.Fatalf("Expected %q but read %q, %v", content, data, err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Expected permissions 0600 but got %o", perm)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("Expected no temporary files to remain but got %d files", len(files))
+	}
+}
+
+func TestCreate_Abort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomicfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	if err := atomicfile.WriteFile(path, []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := atomicfile.Create(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("replacement")
+	f.Abort()
+
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "original" {
+		t.Fatalf("Expected the original file but read %q, %v", data, err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("Expected no temporary files to remain but got %d files", len(files))
+	}
+}
@@ -0,0 +1,66 @@
+package mondo
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// DoFunc performs an HTTP request. Its Do method shares the signature of
+// http.Client.Do, allowing functions to be used as a Client's HTTPClient.
+type DoFunc func(*http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the sending of requests by a Client, e.g. to add logging,
+// metrics or headers. It is called with the next DoFunc in the chain, which it
+// should call to continue with the request.
+type Middleware func(next DoFunc) DoFunc
+
+// Use appends middleware to the Client's chain. Middleware are run in the
+// order they were added, the first being outermost, and wrap every attempt
+// the Client makes to send a request: including retries, requests whose
+// Authorization header has been set by the Client's Auth, and the token
+// refresh requests made by the Auth itself. Use must not be called
+// concurrently with requests.
+func (c *Client) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// send passes the request through the middleware chain to the HTTPClient.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	next := DoFunc(c.HTTPClient.Do)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		next = c.middleware[i](next)
+	}
+	return next(req)
+}
+
+// SetHeader creates middleware which sets a header on each request.
+func SetHeader(key, value string) Middleware {
+	return func(next DoFunc) DoFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set(key, value)
+			return next(req)
+		}
+	}
+}
+
+// LogRequests creates middleware which logs each request once it completes.
+func LogRequests(logger *log.Logger) Middleware {
+	return func(next DoFunc) DoFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			if err != nil {
+				logger.Printf("mondo: {%s} failed after %s: %s", formatReqResp(req, resp), time.Since(start), redactError(err))
+			} else {
+				logger.Printf("mondo: {%s} took %s", formatReqResp(req, resp), time.Since(start))
+			}
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package mondo
+
+import (
+	"github.com/icio/mondo/mondohttp"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClient_Use(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next DoFunc) DoFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+req.Header.Get("Authorization"))
+				return next(req)
+			}
+		}
+	}
+
+	client := &Client{
+		HTTPClient: &scriptedClient{statuses: []int{200}},
+		Auth:       NewAccessTokenAuth("abc"),
+	}
+	client.Use(record("outer"), SetHeader("Authorization", "Overridden"), record("inner"))
+
+	_, err := client.Do(mondohttp.NewAccountsRequest(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"outer Bearer abc", "inner Overridden"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("Expected calls %q but got %q", expected, calls)
+	}
+}
@@ -0,0 +1,100 @@
+// Package mondoauth implements the OAuth2 authorization-code flow through
+// which users grant applications access to their Mondo accounts.
+// https://getmondo.co.uk/docs/#acquire-an-access-token
+package mondoauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondohttp"
+	"net/http"
+	"net/url"
+)
+
+// AuthorizationURL is where users are sent to log in to Mondo.
+const AuthorizationURL string = "https://auth.getmondo.co.uk/"
+
+// ErrStateMismatch indicates an authorization callback whose state parameter
+// didn't match the one issued, suggesting a cross-site request forgery.
+var ErrStateMismatch = errors.New("mondoauth: State parameter mismatch")
+
+// CallbackError is the error returned to the redirect URI when the user
+// doesn't grant access, e.g. "access_denied".
+type CallbackError struct {
+	Code        string
+	Description string
+}
+
+func (err *CallbackError) Error() string {
+	if err.Description == "" {
+		return fmt.Sprintf("mondoauth: Authorization failed (%s)", err.Code)
+	}
+	return fmt.Sprintf("mondoauth: Authorization failed (%s): %s", err.Code, err.Description)
+}
+
+// Config describes an OAuth client registered with Mondo.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURI is the registered URI users return to after logging in.
+	RedirectURI string
+	// Store, when set, receives the token of each UserAuth created.
+	Store mondo.TokenStore
+}
+
+// NewState generates a random, unguessable state parameter.
+func NewState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthCodeURL returns the URL to send the user to for them to log in. The
+// state should be generated by NewState, and kept to validate the callback.
+func (c *Config) AuthCodeURL(state string) string {
+	return AuthorizationURL + "?" + url.Values{
+		"client_id":     {c.ClientID},
+		"redirect_uri":  {c.RedirectURI},
+		"response_type": {"code"},
+		"state":         {state},
+	}.Encode()
+}
+
+// HandleCallback validates the request made to the redirect URI against the
+// state issued, and exchanges its authorization code for a UserAuth.
+func (c *Config) HandleCallback(ctx context.Context, client *mondo.Client, r *http.Request, state string) (*mondo.UserAuth, error) {
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 || state == "" {
+		return nil, ErrStateMismatch
+	}
+	if code := query.Get("error"); code != "" {
+		return nil, &CallbackError{Code: code, Description: query.Get("error_description")}
+	}
+	return c.Exchange(ctx, client, query.Get("code"))
+}
+
+// Exchange swaps an authorization code for a UserAuth, saving its token to the
+// Config's Store if set.
+func (c *Config) Exchange(ctx context.Context, client *mondo.Client, code string) (*mondo.UserAuth, error) {
+	token := new(mondodomain.Token)
+	err := client.DoIntoContext(ctx, mondohttp.NewAuthCodeAccessRequest(c.ClientID, c.ClientSecret, c.RedirectURI, code), token)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := mondo.NewTokenAuth(c.ClientID, c.ClientSecret, token)
+	if c.Store != nil {
+		if err := auth.Persist(c.Store); err != nil {
+			return nil, err
+		}
+	}
+	return auth, nil
+}
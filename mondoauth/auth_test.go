@@ -0,0 +1,73 @@
+package mondoauth
+
+import (
+	"context"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondotest"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestConfig_AuthCodeURL(t *testing.T) {
+	c := &Config{ClientID: "oauthclient_123", RedirectURI: "https://myapp/return"}
+	expected := "https://auth.getmondo.co.uk/?client_id=oauthclient_123&redirect_uri=https%3A%2F%2Fmyapp%2Freturn&response_type=code&state=xyz"
+	if actual := c.AuthCodeURL("xyz"); actual != expected {
+		t.Fatalf("Expected %s but got %s", expected, actual)
+	}
+}
+
+func TestConfig_HandleCallback_State(t *testing.T) {
+	c := &Config{ClientID: mondotest.ClientID, ClientSecret: mondotest.ClientSecret}
+	for _, target := range []string{"/return?code=abc", "/return?code=abc&state=forged"} {
+		r, _ := http.NewRequest("GET", target, nil)
+		if _, err := c.HandleCallback(context.Background(), nil, r, "expected"); err != ErrStateMismatch {
+			t.Errorf("%s: Expected ErrStateMismatch but got %v", target, err)
+		}
+	}
+
+	r, _ := http.NewRequest("GET", "/return?error=access_denied&state=expected", nil)
+	if _, err := c.HandleCallback(context.Background(), nil, r, "expected"); err == nil || err.(*CallbackError).Code != "access_denied" {
+		t.Errorf("Expected an access_denied CallbackError but got %v", err)
+	}
+}
+
+func TestConfig_LoopbackLogin(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := &mondo.Client{HTTPClient: s.HTTPClient()}
+	store := &mondo.MemoryTokenStore{}
+	c := &Config{ClientID: mondotest.ClientID, ClientSecret: mondotest.ClientSecret, Store: store}
+
+	// Play the part of the user's browser, with Mondo redirecting back.
+	open := func(authURL string) error {
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		query := parsed.Query()
+		go func() {
+			resp, err := http.Get(query.Get("redirect_uri") + "?code=" + mondotest.AuthCode + "&state=" + query.Get("state"))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	auth, err := c.LoopbackLogin(ctx, client, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := s.Token()
+	if auth.AccessToken != "Bearer "+token.AccessToken || auth.RefreshToken != token.RefreshToken {
+		t.Fatalf("Expected the issued token but got %#v", auth)
+	}
+	if stored, _ := store.Load(); stored == nil || stored.AccessToken != auth.AccessToken {
+		t.Fatalf("Expected the token to be stored but got %#v", stored)
+	}
+}
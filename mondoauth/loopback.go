@@ -0,0 +1,101 @@
+package mondoauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/icio/mondo"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// DefaultCallbackPath is the path of the loopback callback server, when the
+// Config's RedirectURI doesn't specify one.
+const DefaultCallbackPath string = "/callback"
+
+// LoopbackLogin logs a user in from the command line. It serves the callback
+// on a temporary server on the loopback interface, calls open with the URL for
+// the user to visit (e.g. to launch their browser), and waits for them to
+// return. The Config's RedirectURI, if set, must be a loopback URL such as
+// "http://127.0.0.1:8123/callback", and must be registered with Mondo; when
+// unset, a random port is used.
+func (c *Config) LoopbackLogin(ctx context.Context, client *mondo.Client, open func(authURL string) error) (*mondo.UserAuth, error) {
+	addr, path := "127.0.0.1:0", DefaultCallbackPath
+	if c.RedirectURI != "" {
+		redirect, err := url.Parse(c.RedirectURI)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(redirect.Hostname()); redirect.Scheme != "http" || (redirect.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback())) {
+			return nil, fmt.Errorf("mondoauth: RedirectURI %q is not an http loopback URL", c.RedirectURI)
+		}
+		addr = redirect.Host
+		if redirect.Path != "" {
+			path = redirect.Path
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	config := *c
+	config.RedirectURI = "http://" + listener.Addr().String() + path
+	if c.RedirectURI != "" {
+		config.RedirectURI = c.RedirectURI
+	}
+
+	state, err := NewState()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	// Serve the callback until we get a valid one, handling no more after it.
+	type result struct {
+		auth *mondo.UserAuth
+		err  error
+	}
+	results := make(chan result, 1)
+	var (
+		lock    sync.Mutex
+		handled bool
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if handled {
+			http.Error(w, "Login already handled. You may close this window.", http.StatusGone)
+			return
+		}
+
+		auth, err := config.HandleCallback(ctx, client, r, state)
+		if err == ErrStateMismatch {
+			http.Error(w, "Invalid state.", http.StatusBadRequest)
+			return
+		}
+		handled = true
+		results <- result{auth, err}
+		if err != nil {
+			http.Error(w, "Login failed. You may close this window.", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, "Logged in. You may close this window.")
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := open(config.AuthCodeURL(state)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-results:
+		return res.auth, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -65,17 +65,20 @@ type TransactionsResponse struct {
 // https://getmondo.co.uk/docs/#transactions
 type Transaction struct {
 	ID             string            `json:"id"`
+	AccountID      string            `json:"account_id"`
 	Created        string            `json:"created"`
 	Amount         int               `json:"amount"`
 	Currency       string            `json:"currency"`
 	AccountBalance int               `json:"account_balance"`
 	Merchant       *Merchant         `json:"merchant"`
 	Description    string            `json:"description"`
+	Category       string            `json:"category"`
 	DeclineReason  string            `json:"decline_reason,omitempty"`
 	IsLoad         bool              `json:"is_load"`
 	Settled        string            `json:"settled"`
 	Metadata       map[string]string `json:"metadata"`
 	Notes          string            `json:"notes"`
+	Attachments    []Attachment      `json:"attachments"`
 }
 
 // Merchant is the structure of merchant information on a Transaction. Can be
@@ -89,7 +92,7 @@ type Merchant struct {
 	GroupID  string           `json:"group_id"`
 	Logo     string           `json:"logo"`
 	Emoji    string           `json:"emoji"`
-	Category string           `json:"category"` // Is this also present on the transaction?
+	Category string           `json:"category"`
 }
 
 // rawUnmarshallMerchant is equivalent to Merchant except where decoding json.
@@ -120,3 +123,56 @@ type MerchantAddress struct {
 	Postcode  string  `json:"postcode"`
 	Region    string  `json:"region"`
 }
+
+// WebhookEvent mirrors the payload of events delivered to webhooks, whose Data
+// is decoded according to its Type.
+// https://getmondo.co.uk/docs/#transaction-created
+type WebhookEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// WebhookResponse mirrors the response format of webhook registration
+// requests, and utilises field hoisting to directly expose the wrapped Webhook.
+type WebhookResponse struct {
+	Webhook `json:"webhook"`
+}
+
+// WebhooksResponse mirrors the response format of /webhooks requests.
+// https://getmondo.co.uk/docs/#list-web-hooks
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// Webhook is the structure of a URL registered to receive an account's events.
+// https://getmondo.co.uk/docs/#web-hooks
+type Webhook struct {
+	ID        string `json:"id"`
+	AccountID string `json:"account_id"`
+	URL       string `json:"url"`
+}
+
+// AttachmentUpload mirrors the response format of /attachment/upload requests.
+// https://getmondo.co.uk/docs/#upload-attachment
+type AttachmentUpload struct {
+	FileURL   string `json:"file_url"`
+	UploadURL string `json:"upload_url"`
+}
+
+// AttachmentResponse mirrors the response format of /attachment/register
+// requests, and utilises field hoisting to directly expose the wrapped
+// Attachment.
+type AttachmentResponse struct {
+	Attachment `json:"attachment"`
+}
+
+// Attachment is the structure of a file attached to a Transaction.
+// https://getmondo.co.uk/docs/#attachments
+type Attachment struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	ExternalID string    `json:"external_id"`
+	FileURL    string    `json:"file_url"`
+	FileType   string    `json:"file_type"`
+	Created    time.Time `json:"created"`
+}
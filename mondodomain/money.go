@@ -0,0 +1,170 @@
+package mondodomain
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned by arithmetic on Money of different
+// currencies.
+var ErrCurrencyMismatch = errors.New("mondodomain: Currency mismatch")
+
+// Money is an amount in the minor units of an ISO 4217 currency, e.g. pence
+// for GBP. It is encoded in JSON as a bare integer of minor units, as with the
+// amounts of Transaction and Balance, leaving the currency to be encoded
+// alongside it.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// minorUnits are the ISO 4217 minor unit exponents of currencies which don't
+// have the usual 2.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// symbols are the symbols of currencies which are formatted with one rather
+// than their code.
+var symbols = map[string]string{
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"USD": "$",
+}
+
+// MinorUnits returns the number of digits after the decimal point in amounts
+// of the currency, e.g. 2 for GBP, 0 for JPY and 3 for BHD.
+func MinorUnits(currency string) int {
+	if exp, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Add returns the sum of the amounts, or ErrCurrencyMismatch if they are of
+// different currencies.
+func (m Money) Add(other Money) (Money, error) {
+	if !strings.EqualFold(m.Currency, other.Currency) {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns the difference of the amounts, or ErrCurrencyMismatch if they
+// are of different currencies.
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(Money{Amount: -other.Amount, Currency: other.Currency})
+}
+
+// Locale describes how amounts are written in a locale.
+type Locale struct {
+	Decimal string
+	Group   string
+	// SymbolAfter writes the currency after the amount, e.g. "1.234,56 €".
+	SymbolAfter bool
+}
+
+// Some common locales for formatting Money. LocaleGB also suits other
+// locales writing amounts as "1,234.56", such as the US.
+var (
+	LocaleGB = Locale{Decimal: ".", Group: ","}
+	LocaleDE = Locale{Decimal: ",", Group: ".", SymbolAfter: true}
+	LocaleFR = Locale{Decimal: ",", Group: " ", SymbolAfter: true}
+)
+
+// String formats the amount for LocaleGB, e.g. "£1,234.56" or "-¥500".
+func (m Money) String() string {
+	return m.Format(LocaleGB)
+}
+
+// Format writes the amount with the currency's symbol, or its code when it has
+// no common symbol, in the style of the locale.
+func (m Money) Format(locale Locale) string {
+	exp := MinorUnits(m.Currency)
+	digits := strconv.FormatInt(m.Amount, 10)
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+
+	// Split the major and minor units, grouping the thousands of the major.
+	major, minor := digits[:len(digits)-exp], digits[len(digits)-exp:]
+	var grouped strings.Builder
+	for i, digit := range major {
+		if i > 0 && (len(major)-i)%3 == 0 {
+			grouped.WriteString(locale.Group)
+		}
+		grouped.WriteRune(digit)
+	}
+	number := grouped.String()
+	if exp > 0 {
+		number += locale.Decimal + minor
+	}
+
+	currency := strings.ToUpper(m.Currency)
+	symbol, hasSymbol := symbols[currency]
+	if !hasSymbol {
+		symbol = currency
+	}
+
+	var formatted string
+	switch {
+	case locale.SymbolAfter:
+		formatted = number + " " + symbol
+	case hasSymbol:
+		formatted = symbol + number
+	default:
+		formatted = symbol + " " + number
+	}
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// MarshalJSON encodes the amount as an integer of minor units.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(m.Amount, 10)), nil
+}
+
+// UnmarshalJSON decodes the amount from an integer of minor units, leaving the
+// currency unchanged. As with other types, null leaves the Money unchanged.
+func (m *Money) UnmarshalJSON(body []byte) error {
+	if string(body) == "null" {
+		return nil
+	}
+	amount, err := strconv.ParseInt(string(body), 10, 64)
+	if err != nil {
+		return err
+	}
+	m.Amount = amount
+	return nil
+}
+
+// Money returns the amount of the transaction.
+func (t Transaction) Money() Money {
+	return Money{Amount: int64(t.Amount), Currency: t.Currency}
+}
+
+// AccountBalanceMoney returns the account's balance following the
+// transaction.
+func (t Transaction) AccountBalanceMoney() Money {
+	return Money{Amount: int64(t.AccountBalance), Currency: t.Currency}
+}
+
+// BalanceMoney returns the account's balance.
+func (b Balance) BalanceMoney() Money {
+	return Money{Amount: int64(b.Balance), Currency: b.Currency}
+}
+
+// SpendTodayMoney returns the amount spent from the account today.
+func (b Balance) SpendTodayMoney() Money {
+	return Money{Amount: int64(b.SpendToday), Currency: b.Currency}
+}
@@ -0,0 +1,68 @@
+package mondodomain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoney_Format(t *testing.T) {
+	tests := []struct {
+		money    Money
+		locale   Locale
+		expected string
+	}{
+		{Money{123456, "GBP"}, LocaleGB, "£1,234.56"},
+		{Money{-350, "GBP"}, LocaleGB, "-£3.50"},
+		{Money{5, "GBP"}, LocaleGB, "£0.05"},
+		{Money{0, "USD"}, LocaleGB, "$0.00"},
+		{Money{1000, "JPY"}, LocaleGB, "¥1,000"},
+		{Money{1234, "BHD"}, LocaleGB, "BHD 1.234"},
+		{Money{-5, "BHD"}, LocaleGB, "-BHD 0.005"},
+		{Money{123456789, "EUR"}, LocaleDE, "1.234.567,89 €"},
+		{Money{-99, "EUR"}, LocaleFR, "-0,99 €"},
+		{Money{12345, "CLF"}, LocaleGB, "CLF 1.2345"},
+	}
+	for _, test := range tests {
+		if actual := test.money.Format(test.locale); actual != test.expected {
+			t.Errorf("Expected %#v to format as %q but got %q", test.money, test.expected, actual)
+		}
+	}
+}
+
+func TestMoney_Add(t *testing.T) {
+	sum, err := Money{350, "GBP"}.Add(Money{-820, "gbp"})
+	if err != nil || sum != (Money{-470, "GBP"}) {
+		t.Fatalf("Expected -470 GBP but got %v, %v", sum, err)
+	}
+	if _, err := (Money{350, "GBP"}).Add(Money{350, "EUR"}); err != ErrCurrencyMismatch {
+		t.Fatalf("Expected ErrCurrencyMismatch but got %v", err)
+	}
+	if _, err := (Money{350, "GBP"}).Sub(Money{350, "JPY"}); err != ErrCurrencyMismatch {
+		t.Fatalf("Expected ErrCurrencyMismatch but got %v", err)
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	enc, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Money{-820, "GBP"}})
+	if err != nil || string(enc) != `{"amount":-820}` {
+		t.Fatalf("Expected a bare integer amount but got %s, %v", enc, err)
+	}
+
+	tran := new(Transaction)
+	if err := json.Unmarshal([]byte(`{"amount": -820, "currency": "GBP", "account_balance": 6080}`), tran); err != nil {
+		t.Fatal(err)
+	}
+	if tran.Money() != (Money{-820, "GBP"}) || tran.AccountBalanceMoney().String() != "£60.80" {
+		t.Fatalf("Unexpected amounts %v and %v", tran.Money(), tran.AccountBalanceMoney())
+	}
+
+	m := Money{Currency: "JPY"}
+	if err := json.Unmarshal([]byte(`1500`), &m); err != nil || m != (Money{1500, "JPY"}) {
+		t.Fatalf("Expected 1500 JPY but got %v, %v", m, err)
+	}
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != (Money{1500, "JPY"}) {
+		t.Fatalf("Expected null to leave 1500 JPY but got %v, %v", m, err)
+	}
+}
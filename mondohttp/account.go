@@ -112,5 +112,78 @@ func NewCreateBasicFeedItemRequest(accessToken, accountID, url, title, imageURL,
 	return NewCreateFeedItemRequest(accessToken, accountID, "basic", url, params)
 }
 
-// TODO: https://getmondo.co.uk/docs/#webhooks
-// TODO: https://getmondo.co.uk/docs/#attachments
+// NewRegisterWebhookRequest creates a request for registering a webhook URL
+// to receive an account's events.
+// https://getmondo.co.uk/docs/#registering-a-web-hook
+func NewRegisterWebhookRequest(accessToken, accountID, webhookURL string) *http.Request {
+	body := url.Values{
+		"account_id": {accountID},
+		"url":        {webhookURL},
+	}
+
+	req, _ := http.NewRequest("POST", ProductionAPI+"webhooks", strings.NewReader(body.Encode()))
+	req.Header.Set(formContentType())
+	req.Header.Set(auth(accessToken))
+	return req
+}
+
+// NewWebhooksRequest creates a request for listing an account's webhooks.
+// https://getmondo.co.uk/docs/#list-web-hooks
+func NewWebhooksRequest(accessToken, accountID string) *http.Request {
+	req, _ := http.NewRequest("GET", ProductionAPI+"webhooks?account_id="+url.QueryEscape(accountID), nil)
+	req.Header.Set(auth(accessToken))
+	return req
+}
+
+// NewDeleteWebhookRequest creates a request for deleting a webhook.
+// https://getmondo.co.uk/docs/#deleting-a-web-hook
+func NewDeleteWebhookRequest(accessToken, webhookID string) *http.Request {
+	req, _ := http.NewRequest("DELETE", ProductionAPI+"webhooks/"+url.PathEscape(webhookID), nil)
+	req.Header.Set(auth(accessToken))
+	return req
+}
+
+// NewAttachmentUploadRequest creates a request for a URL to upload an
+// attachment's file to.
+// https://getmondo.co.uk/docs/#upload-attachment
+func NewAttachmentUploadRequest(accessToken, fileName, fileType string) *http.Request {
+	body := url.Values{
+		"file_name": {fileName},
+		"file_type": {fileType},
+	}
+
+	req, _ := http.NewRequest("POST", ProductionAPI+"attachment/upload", strings.NewReader(body.Encode()))
+	req.Header.Set(formContentType())
+	req.Header.Set(auth(accessToken))
+	return req
+}
+
+// NewRegisterAttachmentRequest creates a request for attaching an uploaded
+// file to a transaction.
+// https://getmondo.co.uk/docs/#register-attachment
+func NewRegisterAttachmentRequest(accessToken, transactionID, fileURL, fileType string) *http.Request {
+	body := url.Values{
+		"external_id": {transactionID},
+		"file_url":    {fileURL},
+		"file_type":   {fileType},
+	}
+
+	req, _ := http.NewRequest("POST", ProductionAPI+"attachment/register", strings.NewReader(body.Encode()))
+	req.Header.Set(formContentType())
+	req.Header.Set(auth(accessToken))
+	return req
+}
+
+// NewDeregisterAttachmentRequest creates a request for removing an attachment
+// from its transaction.
+// https://getmondo.co.uk/docs/#deregister-attachment
+func NewDeregisterAttachmentRequest(accessToken, attachmentID string) *http.Request {
+	body := url.Values{
+		"id": {attachmentID},
+	}
+
+	req, _ := http.NewRequest("POST", ProductionAPI+"attachment/deregister", strings.NewReader(body.Encode()))
+	req.Header.Set(formContentType())
+	req.Header.Set(auth(accessToken))
+	return req
+}
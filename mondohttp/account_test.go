@@ -1,6 +1,9 @@
 package mondohttp
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestNewAccountsRequest(t *testing.T) {
 	req := NewAccountsRequest("token")
@@ -62,6 +65,18 @@ Authorization: token
 `)
 }
 
+func TestTransactionsRequest_TimePage(t *testing.T) {
+	since := time.Date(2016, 2, 9, 12, 0, 0, 0, time.UTC)
+	before := time.Date(2016, 2, 10, 13, 30, 0, 500000000, time.FixedZone("BST", 3600))
+	req := NewTransactionsRequest("token", "acc_123", false, FormatTime(since), FormatTime(before), 0)
+	assertReqEquals(t, req, `GET /transactions?account_id=acc_123&before=2016-02-10T12%3A30%3A00.5Z&since=2016-02-09T12%3A00%3A00Z HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Authorization: token
+
+`)
+}
+
 func TestAnnotateTransactionRequest(t *testing.T) {
 	req := NewAnnotateTransactionRequest("token", "trans_456", map[string]string{
 		"test_a": "abc",
@@ -112,3 +127,71 @@ Content-Type: application/x-www-form-urlencoded
 
 account_id=acc_123&params%5Bbackground_color%5D=bg-color&params%5Bbody%5D=You%27ve+created+a+feed+item%21&params%5Bbody_color%5D=p-color&params%5Bimage_url%5D=http%3A%2F%2Ftest.com%2Fimage.png&params%5Btitle%5D=My+feed+item&params%5Btitle_color%5D=h1-color&type=basic&url=https%3A%2F%2Foverride.com%2F`)
 }
+
+func TestRegisterWebhookRequest(t *testing.T) {
+	req := NewRegisterWebhookRequest("token", "acc_123", "https://example.com/hook?token=abc")
+	assertReqEquals(t, req, `POST /webhooks HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Content-Length: 69
+Authorization: token
+Content-Type: application/x-www-form-urlencoded
+
+account_id=acc_123&url=https%3A%2F%2Fexample.com%2Fhook%3Ftoken%3Dabc`)
+}
+
+func TestWebhooksRequest(t *testing.T) {
+	req := NewWebhooksRequest("token", "acc_123")
+	assertReqEquals(t, req, `GET /webhooks?account_id=acc_123 HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Authorization: token
+
+`)
+}
+
+func TestDeleteWebhookRequest(t *testing.T) {
+	req := NewDeleteWebhookRequest("token", "webhook_789")
+	assertReqEquals(t, req, `DELETE /webhooks/webhook_789 HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Authorization: token
+
+`)
+}
+
+func TestAttachmentUploadRequest(t *testing.T) {
+	req := NewAttachmentUploadRequest("token", "receipt.png", "image/png")
+	assertReqEquals(t, req, `POST /attachment/upload HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Content-Length: 43
+Authorization: token
+Content-Type: application/x-www-form-urlencoded
+
+file_name=receipt.png&file_type=image%2Fpng`)
+}
+
+func TestRegisterAttachmentRequest(t *testing.T) {
+	req := NewRegisterAttachmentRequest("token", "tx_456", "https://files.example.com/receipt.png", "image/png")
+	assertReqEquals(t, req, `POST /attachment/register HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Content-Length: 95
+Authorization: token
+Content-Type: application/x-www-form-urlencoded
+
+external_id=tx_456&file_type=image%2Fpng&file_url=https%3A%2F%2Ffiles.example.com%2Freceipt.png`)
+}
+
+func TestDeregisterAttachmentRequest(t *testing.T) {
+	req := NewDeregisterAttachmentRequest("token", "attach_789")
+	assertReqEquals(t, req, `POST /attachment/deregister HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Content-Length: 13
+Authorization: token
+Content-Type: application/x-www-form-urlencoded
+
+id=attach_789`)
+}
@@ -37,6 +37,15 @@ func NewRefreshAccessRequest(clientID, clientSecret, refreshToken string) *http.
 	return req
 }
 
+// NewLogoutRequest creates a request for revoking an access token and the
+// refresh token issued with it.
+// https://getmondo.co.uk/docs/#log-out
+func NewLogoutRequest(accessToken string) *http.Request {
+	req, _ := http.NewRequest("POST", ProductionAPI+"oauth2/logout", nil)
+	req.Header.Set(auth(accessToken))
+	return req
+}
+
 // NewWhoAmIRequest creates a request for verifying the authenticated identity.
 // https://getmondo.co.uk/docs/#authenticating-requests
 func NewWhoAmIRequest(accessToken string) *http.Request {
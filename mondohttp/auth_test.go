@@ -24,6 +24,17 @@ Content-Type: application/x-www-form-urlencoded
 client_id=client_id_123&client_secret=client_sec_abc&grant_type=refresh_token&refresh_token=ref_xyz`)
 }
 
+func TestLogoutRequest(t *testing.T) {
+	req := NewLogoutRequest("token")
+	assertReqEquals(t, req, `POST /oauth2/logout HTTP/1.1
+Host: api.getmondo.co.uk
+User-Agent: Go-http-client/1.1
+Content-Length: 0
+Authorization: token
+
+`)
+}
+
 func TestWhoAmIRequest(t *testing.T) {
 	req := NewWhoAmIRequest("token")
 	assertReqEquals(t, req, `GET /ping/whoami HTTP/1.1
@@ -3,6 +3,7 @@ package mondohttp
 import (
 	"net/url"
 	"strconv"
+	"time"
 )
 
 // ProductionAPI is the base URL of Mondo's production API.
@@ -20,6 +21,12 @@ func formContentType() (string, string) {
 	return "Content-Type", "application/x-www-form-urlencoded"
 }
 
+// FormatTime formats a time for the since and before parameters of paginated
+// requests, which accept RFC3339 timestamps.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
 func appendPaginationParams(query *url.Values, since, before string, limit int) {
 	if since != "" {
 		query.Set("since", since)
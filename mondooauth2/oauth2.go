@@ -0,0 +1,114 @@
+// Package mondooauth2 adapts mondo's authentication to and from the
+// golang.org/x/oauth2 package.
+package mondooauth2
+
+import (
+	"context"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondodomain"
+	"golang.org/x/oauth2"
+	"strings"
+	"time"
+)
+
+// Token converts a token response from Mondo into an oauth2.Token, computing
+// its Expiry from ExpiresIn. The UserID and ClientID are kept as extras.
+func Token(token *mondodomain.Token) *oauth2.Token {
+	t := &oauth2.Token{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		RefreshToken: token.RefreshToken,
+	}
+	if token.ExpiresIn > 0 {
+		t.ExpiresIn = int64(token.ExpiresIn)
+		t.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return t.WithExtra(map[string]interface{}{
+		"user_id":   token.UserID,
+		"client_id": token.ClientID,
+	})
+}
+
+// FromToken converts an oauth2.Token into the form of Mondo's token
+// responses, computing ExpiresIn from Expiry.
+func FromToken(t *oauth2.Token) *mondodomain.Token {
+	token := &mondodomain.Token{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.Type(),
+		RefreshToken: t.RefreshToken,
+	}
+	if !t.Expiry.IsZero() {
+		if remaining := time.Until(t.Expiry); remaining > 0 {
+			token.ExpiresIn = uint64((remaining + time.Second - 1) / time.Second)
+		}
+	}
+	if userID, ok := t.Extra("user_id").(string); ok {
+		token.UserID = userID
+	}
+	if clientID, ok := t.Extra("client_id").(string); ok {
+		token.ClientID = clientID
+	}
+	return token
+}
+
+// TokenSource provides the access tokens of a UserAuth as an
+// oauth2.TokenSource, refreshing them through the Client as needed.
+func TokenSource(ctx context.Context, auth *mondo.UserAuth, client *mondo.Client) oauth2.TokenSource {
+	return &userAuthSource{ctx: ctx, auth: auth, client: client}
+}
+
+type userAuthSource struct {
+	ctx    context.Context
+	auth   *mondo.UserAuth
+	client *mondo.Client
+}
+
+// Token returns the auth's current token, with its fields read together so
+// that a concurrent refresh can't mix the old token with the new.
+func (s *userAuthSource) Token() (*oauth2.Token, error) {
+	var header, refreshToken string
+	var expiry time.Time
+	for {
+		accessToken, err := s.auth.GetContext(s.ctx, false, s.client)
+		if err != nil {
+			return nil, err
+		}
+
+		s.auth.Lock.RLock()
+		header, refreshToken, expiry = s.auth.AccessToken, s.auth.RefreshToken, s.auth.Expiry
+		s.auth.Lock.RUnlock()
+		if header == accessToken {
+			break
+		}
+		// The token changed since GetContext returned, so check it again.
+	}
+
+	t := &oauth2.Token{AccessToken: header, RefreshToken: refreshToken, Expiry: expiry}
+	if i := strings.IndexByte(header, ' '); i >= 0 {
+		t.TokenType, t.AccessToken = header[:i], header[i+1:]
+	}
+	return t, nil
+}
+
+// Auth provides the tokens of an oauth2.TokenSource as a mondo.Client's Auth.
+// A TokenSource can't be made to discard a token the API rejected, so the
+// Client's retry of such requests uses the token the source next returns.
+type Auth struct {
+	Source oauth2.TokenSource
+}
+
+// NewAuth prepares an Auth for the given source. Wrap sources in
+// oauth2.ReuseTokenSource to avoid fetching a token per request.
+func NewAuth(source oauth2.TokenSource) *Auth {
+	return &Auth{Source: source}
+}
+
+// Get returns an Authorization header value (e.g. "Bearer xyz...") for the
+// source's current token.
+func (a *Auth) Get(invalidate bool, client *mondo.Client) (string, error) {
+	t, err := a.Source.Token()
+	if err != nil {
+		return "", err
+	}
+	return t.Type() + " " + t.AccessToken, nil
+}
@@ -0,0 +1,65 @@
+package mondooauth2
+
+import (
+	"context"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondotest"
+	"golang.org/x/oauth2"
+	"testing"
+	"time"
+)
+
+func TestToken_RoundTrip(t *testing.T) {
+	token := &mondodomain.Token{
+		AccessToken:  "access",
+		ClientID:     "oauthclient_123",
+		ExpiresIn:    21600,
+		RefreshToken: "refresh",
+		TokenType:    "Bearer",
+		UserID:       "user_123",
+	}
+
+	converted := Token(token)
+	if until := time.Until(converted.Expiry); until < 21599*time.Second || until > 21600*time.Second {
+		t.Fatalf("Expected expiry in 6 hours but got %s", until)
+	}
+	if back := FromToken(converted); *back != *token {
+		t.Fatalf("Expected %#v but got %#v", token, back)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	s.ExpireToken()
+
+	// The UserAuth's token is refreshed as we force it to be rejected.
+	auth := client.Auth.(*mondo.UserAuth)
+	if _, err := auth.Get(true, client); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := TokenSource(context.Background(), auth, client).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := s.Token()
+	if token.AccessToken != expected.AccessToken || token.TokenType != "Bearer" || token.RefreshToken != expected.RefreshToken || token.Expiry.IsZero() {
+		t.Fatalf("Expected %#v but got %#v", expected, token)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	client := &mondo.Client{
+		HTTPClient: s.HTTPClient(),
+		Auth:       NewAuth(oauth2.StaticTokenSource(Token(&mondodomain.Token{AccessToken: s.Token().AccessToken}))),
+	}
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
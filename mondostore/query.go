@@ -0,0 +1,126 @@
+package mondostore
+
+import (
+	"github.com/icio/mondo/mondodomain"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query selects stored transactions. Transactions must match each of the
+// criteria given; those left as their zero value match all transactions.
+type Query struct {
+	AccountID string
+
+	// Since and Before bound the transactions' created time, from Since
+	// inclusive to Before exclusive.
+	Since  time.Time
+	Before time.Time
+
+	// MinAmount and MaxAmount bound the transactions' amounts inclusively, in
+	// minor units. Spending is negative.
+	MinAmount *int
+	MaxAmount *int
+
+	MerchantID string
+	Category   string
+
+	// Metadata are key-value pairs which must all be present in the
+	// transactions' metadata.
+	Metadata map[string]string
+
+	// Text is searched for, case-insensitively, in the transactions'
+	// Description and Notes.
+	Text string
+
+	// Limit is the maximum number of transactions returned, if positive.
+	Limit int
+}
+
+// Amount returns a pointer to the amount, for use in a Query.
+func Amount(amount int) *int {
+	return &amount
+}
+
+// Transactions returns the stored transactions matching the query, oldest
+// first.
+func (s *Store) Transactions(q Query) []mondodomain.Transaction {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var trans []mondodomain.Transaction
+	for _, r := range s.candidates(&q) {
+		if q.Limit > 0 && len(trans) == q.Limit {
+			break
+		}
+		if q.matches(r) {
+			trans = append(trans, r.tran)
+		}
+	}
+	return trans
+}
+
+// candidates returns the records which may match the query, in created order,
+// using the merchant or category index where possible and otherwise the range
+// of byCreated. Requires the read lock.
+func (s *Store) candidates(q *Query) []*record {
+	var idx map[string]*record
+	if q.MerchantID != "" {
+		idx = s.byMerchant[q.MerchantID]
+	}
+	if q.Category != "" && (idx == nil || len(s.byCategory[q.Category]) < len(idx)) {
+		idx = s.byCategory[q.Category]
+	}
+	if q.MerchantID != "" || q.Category != "" {
+		records := make([]*record, 0, len(idx))
+		for _, r := range idx {
+			records = append(records, r)
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].before(records[j]) })
+		return records
+	}
+
+	records := s.byCreated
+	if !q.Since.IsZero() {
+		i := sort.Search(len(records), func(i int) bool { return !records[i].created.Before(q.Since) })
+		records = records[i:]
+	}
+	if !q.Before.IsZero() {
+		i := sort.Search(len(records), func(i int) bool { return !records[i].created.Before(q.Before) })
+		records = records[:i]
+	}
+	return records
+}
+
+// matches reports whether the record satisfies all of the query's criteria.
+func (q *Query) matches(r *record) bool {
+	tran := &r.tran
+	switch {
+	case q.AccountID != "" && tran.AccountID != q.AccountID:
+		return false
+	case !q.Since.IsZero() && r.created.Before(q.Since):
+		return false
+	case !q.Before.IsZero() && !r.created.Before(q.Before):
+		return false
+	case q.MinAmount != nil && tran.Amount < *q.MinAmount:
+		return false
+	case q.MaxAmount != nil && tran.Amount > *q.MaxAmount:
+		return false
+	case q.MerchantID != "" && merchantID(tran) != q.MerchantID:
+		return false
+	case q.Category != "" && tran.Category != q.Category:
+		return false
+	}
+	for key, value := range q.Metadata {
+		if v, ok := tran.Metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(tran.Description), text) && !strings.Contains(strings.ToLower(tran.Notes), text) {
+			return false
+		}
+	}
+	return true
+}
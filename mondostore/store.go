@@ -0,0 +1,375 @@
+// Package mondostore keeps an offline copy of Mondo accounts, merchants and
+// transactions in a local file, indexed for querying without network access.
+package mondostore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/icio/mondo/internal/atomicfile"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondosync"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by writes to a closed Store.
+var ErrClosed = errors.New("mondostore: Store is closed")
+
+// entry is a line of the store's file, holding one of its records.
+type entry struct {
+	Account     *mondodomain.Account     `json:"account,omitempty"`
+	Merchant    *mondodomain.Merchant    `json:"merchant,omitempty"`
+	Transaction *mondodomain.Transaction `json:"transaction,omitempty"`
+}
+
+// record is a stored transaction with its parsed created time.
+type record struct {
+	tran    mondodomain.Transaction
+	created time.Time
+}
+
+// before orders records by their created time, then ID.
+func (r *record) before(other *record) bool {
+	if !r.created.Equal(other.created) {
+		return r.created.Before(other.created)
+	}
+	return r.tran.ID < other.tran.ID
+}
+
+// Store holds accounts, merchants and transactions in memory, indexed by
+// transactions' created time, merchant and category. Each change is appended
+// to the store's file as a line of JSON, which is replayed by Open; Compact
+// rewrites the file without the records since replaced. Thread-safe within a
+// process, though the file mustn't be opened by more than one Store at once.
+type Store struct {
+	// Sync has each change flushed to disk before it is applied, so that it
+	// survives a crash of the machine. Set it before using the Store.
+	Sync bool
+
+	path string
+	lock sync.RWMutex
+	file *os.File
+
+	accounts     map[string]mondodomain.Account
+	merchants    map[string]mondodomain.Merchant
+	transactions map[string]*record
+
+	// Indexes of transactions.
+	byCreated  []*record
+	byMerchant map[string]map[string]*record
+	byCategory map[string]map[string]*record
+}
+
+// Open loads the store at the given path, creating its file if it doesn't
+// exist. A partially written record at the end of the file, as left by a
+// crash, is discarded.
+func Open(path string) (*Store, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		path:         path,
+		file:         file,
+		accounts:     make(map[string]mondodomain.Account),
+		merchants:    make(map[string]mondodomain.Merchant),
+		transactions: make(map[string]*record),
+		byMerchant:   make(map[string]map[string]*record),
+		byCategory:   make(map[string]map[string]*record),
+	}
+	if err := s.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// replay applies each of the file's entries, truncating any partial entry at
+// its end, and leaves the file positioned for appending.
+func (s *Store) replay() error {
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// The final entry was only partially written.
+				if err := s.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		} else if err != nil {
+			return err
+		}
+
+		e := new(entry)
+		if err := json.Unmarshal(line, e); err != nil {
+			return err
+		}
+		s.apply(e)
+		offset += int64(len(line))
+	}
+
+	for _, r := range s.transactions {
+		s.byCreated = append(s.byCreated, r)
+	}
+	sort.Slice(s.byCreated, func(i, j int) bool {
+		return s.byCreated[i].before(s.byCreated[j])
+	})
+	_, err := s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// apply adds the entry's record to the maps and indexes, except byCreated,
+// which is left to the caller. Requires the lock.
+func (s *Store) apply(e *entry) {
+	if e.Account != nil {
+		s.accounts[e.Account.ID] = *e.Account
+	}
+	if e.Merchant != nil {
+		s.merchants[e.Merchant.ID] = *e.Merchant
+	}
+	if e.Transaction != nil {
+		if prev, ok := s.transactions[e.Transaction.ID]; ok {
+			s.unindex(prev)
+		}
+		r := &record{tran: *e.Transaction}
+		r.created, _ = time.Parse(time.RFC3339, r.tran.Created)
+		s.transactions[r.tran.ID] = r
+		index(s.byMerchant, merchantID(&r.tran), r)
+		index(s.byCategory, r.tran.Category, r)
+	}
+}
+
+// unindex removes the record from the merchant and category indexes.
+// Requires the lock.
+func (s *Store) unindex(r *record) {
+	delete(s.byMerchant[merchantID(&r.tran)], r.tran.ID)
+	delete(s.byCategory[r.tran.Category], r.tran.ID)
+}
+
+func index(idx map[string]map[string]*record, key string, r *record) {
+	if key == "" {
+		return
+	}
+	if idx[key] == nil {
+		idx[key] = make(map[string]*record)
+	}
+	idx[key][r.tran.ID] = r
+}
+
+func merchantID(tran *mondodomain.Transaction) string {
+	if tran.Merchant == nil {
+		return ""
+	}
+	return tran.Merchant.ID
+}
+
+// write appends the entry to the file, then applies it. A failed write is
+// truncated from the file, leaving it as it was. Requires the lock.
+func (s *Store) write(e *entry) error {
+	if s.file == nil {
+		return ErrClosed
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	offset, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(append(data, '\n')); err == nil && s.Sync {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		if s.file.Truncate(offset) == nil {
+			s.file.Seek(offset, io.SeekStart)
+		}
+		return err
+	}
+
+	var prev *record
+	if e.Transaction != nil {
+		prev = s.transactions[e.Transaction.ID]
+	}
+	s.apply(e)
+	if e.Transaction != nil {
+		s.reorder(prev, s.transactions[e.Transaction.ID])
+	}
+	return nil
+}
+
+// reorder replaces prev with next in byCreated. Requires the lock.
+func (s *Store) reorder(prev, next *record) {
+	if prev != nil {
+		i := sort.Search(len(s.byCreated), func(i int) bool { return !s.byCreated[i].before(prev) })
+		s.byCreated = append(s.byCreated[:i], s.byCreated[i+1:]...)
+	}
+	i := sort.Search(len(s.byCreated), func(i int) bool { return !s.byCreated[i].before(next) })
+	s.byCreated = append(s.byCreated, nil)
+	copy(s.byCreated[i+1:], s.byCreated[i:])
+	s.byCreated[i] = next
+}
+
+// PutAccount stores the account, replacing any with the same ID.
+func (s *Store) PutAccount(account mondodomain.Account) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if prev, ok := s.accounts[account.ID]; ok && reflect.DeepEqual(prev, account) {
+		return nil
+	}
+	return s.write(&entry{Account: &account})
+}
+
+// PutMerchant stores the merchant, replacing any with the same ID.
+func (s *Store) PutMerchant(merchant mondodomain.Merchant) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.putMerchant(merchant)
+}
+
+func (s *Store) putMerchant(merchant mondodomain.Merchant) error {
+	if prev, ok := s.merchants[merchant.ID]; ok && reflect.DeepEqual(prev, merchant) {
+		return nil
+	}
+	return s.write(&entry{Merchant: &merchant})
+}
+
+// UpsertTransaction stores the transaction, replacing any with the same ID,
+// and satisfies mondosync.Sink. The details of expanded merchants are stored
+// with PutMerchant.
+func (s *Store) UpsertTransaction(tran mondodomain.Transaction) (mondosync.Outcome, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if tran.Merchant != nil && tran.Merchant.Name != "" {
+		if err := s.putMerchant(*tran.Merchant); err != nil {
+			return 0, err
+		}
+	}
+
+	outcome := mondosync.Inserted
+	if prev, ok := s.transactions[tran.ID]; ok {
+		if reflect.DeepEqual(prev.tran, tran) {
+			return mondosync.Unchanged, nil
+		}
+		outcome = mondosync.Updated
+	}
+	if err := s.write(&entry{Transaction: &tran}); err != nil {
+		return 0, err
+	}
+	return outcome, nil
+}
+
+// Account returns the account with the given ID, if stored.
+func (s *Store) Account(id string) (mondodomain.Account, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	account, ok := s.accounts[id]
+	return account, ok
+}
+
+// Accounts returns all stored accounts, ordered by ID.
+func (s *Store) Accounts() []mondodomain.Account {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	accounts := make([]mondodomain.Account, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts
+}
+
+// Merchant returns the merchant with the given ID, if stored.
+func (s *Store) Merchant(id string) (mondodomain.Merchant, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	merchant, ok := s.merchants[id]
+	return merchant, ok
+}
+
+// Transaction returns the transaction with the given ID, if stored.
+func (s *Store) Transaction(id string) (mondodomain.Transaction, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	r, ok := s.transactions[id]
+	if !ok {
+		return mondodomain.Transaction{}, false
+	}
+	return r.tran, true
+}
+
+// Compact rewrites the store's file with only its current records, dropping
+// those since replaced.
+func (s *Store) Compact() (err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return ErrClosed
+	}
+
+	tmp, err := atomicfile.Create(s.path, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Abort()
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, account := range s.accounts {
+		if err = enc.Encode(&entry{Account: &account}); err != nil {
+			return err
+		}
+	}
+	for _, merchant := range s.merchants {
+		if err = enc.Encode(&entry{Merchant: &merchant}); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.byCreated {
+		if err = enc.Encode(&entry{Transaction: &r.tran}); err != nil {
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Commit(); err != nil {
+		return err
+	}
+
+	// Continue appending to the compacted file.
+	s.file.Close()
+	s.file = tmp.File
+	return nil
+}
+
+// Close flushes the store's file to disk and closes it. The store can still be
+// read from, but not written to.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Sync()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	return err
+}
@@ -0,0 +1,148 @@
+package mondostore_test
+
+import (
+	"context"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondostore"
+	"github.com/icio/mondo/mondosync"
+	"github.com/icio/mondo/mondotest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func tempStore(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "mondostore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "store.jsonl"), func() { os.RemoveAll(dir) }
+}
+
+func ids(trans []mondodomain.Transaction) []string {
+	ids := []string{}
+	for _, tran := range trans {
+		ids = append(ids, tran.ID)
+	}
+	return ids
+}
+
+func TestStore_Query(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	path, cleanup := tempStore(t)
+	defer cleanup()
+
+	store, err := mondostore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncer := &mondosync.Syncer{
+		Client:          s.NewClient(),
+		Checkpoints:     &mondosync.MemoryCheckpointStore{},
+		Sink:            store,
+		ExpandMerchants: true,
+	}
+	if _, err := syncer.Sync(context.Background(), "acc_00000001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.NewClient().Annotate(context.Background(), "tx_00000003", map[string]string{"trip": "weekly"}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := syncer.Sync(context.Background(), "acc_00000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 || result.Unchanged != 4 {
+		t.Fatalf("Expected 1 updated and 4 unchanged but got %+v", result)
+	}
+
+	day := func(d int) time.Time { return time.Date(2016, 2, 9+d, 12, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		query    mondostore.Query
+		expected []string
+	}{
+		{"all", mondostore.Query{}, []string{"tx_00000001", "tx_00000002", "tx_00000003", "tx_00000004", "tx_00000005"}},
+		{"date range", mondostore.Query{Since: day(1), Before: day(3)}, []string{"tx_00000002", "tx_00000003"}},
+		{"amount range", mondostore.Query{MinAmount: mondostore.Amount(-1000), MaxAmount: mondostore.Amount(0)}, []string{"tx_00000002", "tx_00000004", "tx_00000005"}},
+		{"merchant", mondostore.Query{MerchantID: "merch_coffee"}, []string{"tx_00000002", "tx_00000004"}},
+		{"category", mondostore.Query{Category: "groceries", Since: day(3)}, []string{"tx_00000005"}},
+		{"metadata", mondostore.Query{Metadata: map[string]string{"trip": "weekly"}}, []string{"tx_00000003"}},
+		{"text", mondostore.Query{Text: "coffee"}, []string{"tx_00000002", "tx_00000004"}},
+		{"limit", mondostore.Query{AccountID: "acc_00000001", Limit: 2}, []string{"tx_00000001", "tx_00000002"}},
+		{"none", mondostore.Query{AccountID: "acc_other"}, []string{}},
+	}
+	check := func(store *mondostore.Store) {
+		for _, test := range tests {
+			if actual := ids(store.Transactions(test.query)); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("%s: expected %q but got %q", test.name, test.expected, actual)
+			}
+		}
+		if merchant, ok := store.Merchant("merch_grocer"); !ok || merchant.Name != "The Grocer" {
+			t.Errorf("Expected the grocer's details but got %#v", merchant)
+		}
+	}
+	check(store)
+
+	// The records and indexes are restored when reopened, and after
+	// compaction.
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = mondostore.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	check(store)
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if store, err = mondostore.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	check(store)
+}
+
+func TestStore_PartialWrite(t *testing.T) {
+	path, cleanup := tempStore(t)
+	defer cleanup()
+
+	store, err := mondostore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PutAccount(mondodomain.Account{ID: "acc_1"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// Simulate a crash part way through writing a record.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"account": {"id": "acc_`)
+	file.Close()
+
+	if store, err = mondostore.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	store.Sync = true
+	if err := store.PutAccount(mondodomain.Account{ID: "acc_2"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if store, err = mondostore.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if accounts := store.Accounts(); len(accounts) != 2 || accounts[1].ID != "acc_2" {
+		t.Fatalf("Expected acc_1 and acc_2 but got %#v", accounts)
+	}
+}
@@ -0,0 +1,117 @@
+package mondosync
+
+import (
+	"encoding/json"
+	"github.com/icio/mondo/internal/atomicfile"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Checkpoint records how far an account's transactions have been synced.
+type Checkpoint struct {
+	// TransactionID and Created identify the newest transaction synced.
+	TransactionID string    `json:"transaction_id"`
+	Created       time.Time `json:"created"`
+}
+
+// CheckpointStore persists a Checkpoint per account. Implementations must be
+// thread-safe.
+type CheckpointStore interface {
+	// Load returns the account's checkpoint, or nil if it has never been
+	// synced.
+	Load(accountID string) (*Checkpoint, error)
+	// Save replaces the account's checkpoint.
+	Save(accountID string, checkpoint Checkpoint) error
+}
+
+// MemoryCheckpointStore is a CheckpointStore holding checkpoints in memory,
+// e.g. for tests. Thread-safe.
+type MemoryCheckpointStore struct {
+	lock        sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// Load satisfies CheckpointStore.
+func (s *MemoryCheckpointStore) Load(accountID string) (*Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	checkpoint, ok := s.checkpoints[accountID]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+// Save satisfies CheckpointStore.
+func (s *MemoryCheckpointStore) Save(accountID string, checkpoint Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.checkpoints == nil {
+		s.checkpoints = make(map[string]Checkpoint)
+	}
+	s.checkpoints[accountID] = checkpoint
+	return nil
+}
+
+// FileCheckpointStore is a CheckpointStore keeping the checkpoints of all
+// accounts in a single JSON file, which is replaced atomically on each Save.
+// Thread-safe within a process.
+type FileCheckpointStore struct {
+	Path string
+	lock sync.Mutex
+}
+
+// NewFileCheckpointStore prepares a store for the checkpoint file at the given
+// path.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{Path: path}
+}
+
+// Load satisfies CheckpointStore.
+func (s *FileCheckpointStore) Load(accountID string) (*Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	checkpoints, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, ok := checkpoints[accountID]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+// Save satisfies CheckpointStore.
+func (s *FileCheckpointStore) Save(accountID string, checkpoint Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	checkpoints, err := s.load()
+	if err != nil {
+		return err
+	}
+	checkpoints[accountID] = checkpoint
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.Path, data, 0600)
+}
+
+func (s *FileCheckpointStore) load() (map[string]Checkpoint, error) {
+	checkpoints := make(map[string]Checkpoint)
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
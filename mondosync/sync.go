@@ -0,0 +1,165 @@
+// Package mondosync incrementally copies accounts' transactions from the
+// Mondo API into a Sink, remembering how far each account has been synced.
+package mondosync
+
+import (
+	"context"
+	"fmt"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondodomain"
+	"time"
+)
+
+// DefaultLookback is how far before its checkpoint an account's transactions
+// are re-fetched, when Syncer.Lookback is unset. Transactions are usually
+// settled, or declined, within a few days of being created.
+const DefaultLookback = 7 * 24 * time.Hour
+
+// Outcome describes how a Sink handled a transaction.
+type Outcome int
+
+// The outcomes of upserting a transaction.
+const (
+	// Inserted indicates the transaction was new to the Sink.
+	Inserted Outcome = iota
+	// Updated indicates the transaction replaced an older copy of itself.
+	Updated
+	// Unchanged indicates the Sink already had the same copy.
+	Unchanged
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Inserted:
+		return "inserted"
+	case Updated:
+		return "updated"
+	case Unchanged:
+		return "unchanged"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// Sink stores the transactions fetched by a Syncer.
+type Sink interface {
+	// UpsertTransaction inserts the transaction, or replaces any transaction
+	// with the same ID.
+	UpsertTransaction(tran mondodomain.Transaction) (Outcome, error)
+}
+
+// Result reports the outcome of syncing an account.
+type Result struct {
+	AccountID  string
+	Inserted   int
+	Updated    int
+	Unchanged  int
+	Checkpoint *Checkpoint
+}
+
+// Syncer fetches the transactions of accounts created since they were last
+// synced, passing them to the Sink. Transactions within Lookback of the
+// checkpoint are fetched again, picking up those settled or declined since.
+type Syncer struct {
+	Client      *mondo.Client
+	Checkpoints CheckpointStore
+	Sink        Sink
+
+	// Lookback is how far before the checkpoint to re-fetch transactions,
+	// defaulting to DefaultLookback. Negative values disable the lookback.
+	Lookback        time.Duration
+	ExpandMerchants bool
+	PageSize        int
+}
+
+// Sync fetches the account's new and recent transactions into the Sink and
+// advances its checkpoint. Should syncing fail part way, the checkpoint is
+// advanced past the transactions already synced before the error is returned.
+func (s *Syncer) Sync(ctx context.Context, accountID string) (*Result, error) {
+	checkpoint, err := s.Checkpoints.Load(accountID)
+	if err != nil {
+		return nil, mondo.WrapError(err, "Failed to load sync checkpoint", nil, nil)
+	}
+
+	opts := mondo.TransactionsOptions{
+		AccountID:       accountID,
+		ExpandMerchants: s.ExpandMerchants,
+		PageSize:        s.PageSize,
+	}
+	if checkpoint != nil {
+		if lookback := s.lookback(); lookback < 0 {
+			opts.Since = checkpoint.TransactionID
+		} else {
+			opts.SinceTime = checkpoint.Created.Add(-lookback)
+		}
+	}
+
+	result := &Result{AccountID: accountID, Checkpoint: checkpoint}
+	it := s.Client.Transactions(ctx, opts)
+	for it.Next() {
+		tran := it.Transaction()
+		outcome, err := s.Sink.UpsertTransaction(*tran)
+		if err != nil {
+			return result, s.save(result, mondo.WrapError(err, "Failed to store transaction "+tran.ID, nil, nil))
+		}
+		result.add(tran, outcome)
+	}
+	return result, s.save(result, it.Err())
+}
+
+// SyncAll syncs each of the user's accounts in turn, stopping at the first
+// error.
+func (s *Syncer) SyncAll(ctx context.Context) ([]*Result, error) {
+	accounts, err := s.Client.Accounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(accounts))
+	for _, account := range accounts {
+		result, err := s.Sync(ctx, account.ID)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (s *Syncer) lookback() time.Duration {
+	if s.Lookback == 0 {
+		return DefaultLookback
+	}
+	return s.Lookback
+}
+
+// save persists the result's checkpoint, returning syncErr or else any error
+// saving.
+func (s *Syncer) save(result *Result, syncErr error) error {
+	if result.Checkpoint == nil {
+		return syncErr
+	}
+	if err := s.Checkpoints.Save(result.AccountID, *result.Checkpoint); err != nil && syncErr == nil {
+		return mondo.WrapError(err, "Failed to save sync checkpoint", nil, nil)
+	}
+	return syncErr
+}
+
+// add counts the outcome, and advances the checkpoint to the transaction if it
+// is the newest seen.
+func (r *Result) add(tran *mondodomain.Transaction, outcome Outcome) {
+	switch outcome {
+	case Inserted:
+		r.Inserted++
+	case Updated:
+		r.Updated++
+	default:
+		r.Unchanged++
+	}
+
+	created, err := time.Parse(time.RFC3339, tran.Created)
+	if err != nil {
+		return
+	}
+	if r.Checkpoint == nil || !created.Before(r.Checkpoint.Created) {
+		r.Checkpoint = &Checkpoint{TransactionID: tran.ID, Created: created}
+	}
+}
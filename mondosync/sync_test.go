@@ -0,0 +1,116 @@
+package mondosync_test
+
+import (
+	"context"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondosync"
+	"github.com/icio/mondo/mondotest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mapSink is a Sink keeping transactions in a map.
+type mapSink map[string]mondodomain.Transaction
+
+func (s mapSink) UpsertTransaction(tran mondodomain.Transaction) (mondosync.Outcome, error) {
+	prev, ok := s[tran.ID]
+	s[tran.ID] = tran
+	if !ok {
+		return mondosync.Inserted, nil
+	} else if reflect.DeepEqual(prev, tran) {
+		return mondosync.Unchanged, nil
+	}
+	return mondosync.Updated, nil
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	ctx := context.Background()
+
+	sink := mapSink{}
+	syncer := &mondosync.Syncer{
+		Client:      client,
+		Checkpoints: &mondosync.MemoryCheckpointStore{},
+		Sink:        sink,
+		Lookback:    36 * time.Hour,
+		PageSize:    2,
+	}
+
+	// The first sync fetches the whole history.
+	result, err := syncer.Sync(ctx, "acc_00000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 5 || result.Checkpoint == nil || result.Checkpoint.TransactionID != "tx_00000005" {
+		t.Fatalf("Unexpected result of first sync: %+v", result)
+	}
+
+	// Subsequent syncs pick up new transactions, and changes to those within
+	// the lookback of the checkpoint.
+	if _, err := client.Annotate(ctx, "tx_00000004", map[string]string{"note": "settled"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Annotate(ctx, "tx_00000002", map[string]string{"note": "too old"}); err != nil {
+		t.Fatal(err)
+	}
+	s.AddTransaction(mondodomain.Transaction{
+		ID:        "tx_00000006",
+		AccountID: "acc_00000001",
+		Created:   time.Date(2016, 2, 14, 12, 0, 0, 0, time.UTC).Format(time.RFC3339),
+		Amount:    -100,
+		Currency:  "GBP",
+		Metadata:  map[string]string{},
+	})
+
+	result, err = syncer.Sync(ctx, "acc_00000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := mondosync.Result{AccountID: "acc_00000001", Inserted: 1, Updated: 1, Unchanged: 1}
+	checkpoint := result.Checkpoint
+	result.Checkpoint = nil
+	if !reflect.DeepEqual(*result, expected) {
+		t.Fatalf("Expected %+v but got %+v", expected, *result)
+	}
+	if checkpoint.TransactionID != "tx_00000006" {
+		t.Fatalf("Expected checkpoint at tx_00000006 but got %+v", checkpoint)
+	}
+	if sink["tx_00000002"].Metadata["note"] != "" {
+		t.Fatal("Expected tx_00000002 to be outside of the lookback")
+	}
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mondosync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := mondosync.NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json"))
+
+	if checkpoint, err := store.Load("acc_1"); err != nil || checkpoint != nil {
+		t.Fatalf("Expected no checkpoint but got %v, %v", checkpoint, err)
+	}
+
+	saved := mondosync.Checkpoint{TransactionID: "tx_1", Created: time.Date(2016, 2, 9, 12, 0, 0, 0, time.UTC)}
+	if err := store.Save("acc_1", saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("acc_2", mondosync.Checkpoint{TransactionID: "tx_2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := mondosync.NewFileCheckpointStore(store.Path).Load("acc_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded == nil || loaded.TransactionID != saved.TransactionID || !loaded.Created.Equal(saved.Created) {
+		t.Fatalf("Expected %+v but got %+v", saved, loaded)
+	}
+}
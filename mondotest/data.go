@@ -0,0 +1,83 @@
+package mondotest
+
+import (
+	"github.com/icio/mondo/mondodomain"
+	"time"
+)
+
+// Data is the state the fake API serves. Transactions refer to their account
+// by AccountID, and are served in the order of their Created time.
+type Data struct {
+	UserID       string
+	Accounts     []mondodomain.Account
+	Balances     map[string]mondodomain.Balance
+	Transactions []mondodomain.Transaction
+	Webhooks     []mondodomain.Webhook
+}
+
+// FeedItem records a feed item created through the fake API.
+type FeedItem struct {
+	AccountID string
+	Type      string
+	URL       string
+	Params    map[string]string
+}
+
+// SeedData returns a small data set of one account with a handful of
+// transactions, some at merchants.
+func SeedData() *Data {
+	created := time.Date(2016, 2, 9, 12, 0, 0, 0, time.UTC)
+	coffee := &mondodomain.Merchant{
+		ID:       "merch_coffee",
+		Name:     "Ozone Coffee Roasters",
+		Created:  created,
+		GroupID:  "grp_coffee",
+		Emoji:    "☕",
+		Category: "eating_out",
+	}
+	grocer := &mondodomain.Merchant{
+		ID:       "merch_grocer",
+		Name:     "The Grocer",
+		Created:  created,
+		GroupID:  "grp_grocer",
+		Emoji:    "🍏",
+		Category: "groceries",
+	}
+
+	transaction := func(id string, day int, amount int, merchant *mondodomain.Merchant, description string) mondodomain.Transaction {
+		category := "mondo"
+		if merchant != nil {
+			category = merchant.Category
+		}
+		return mondodomain.Transaction{
+			ID:          id,
+			AccountID:   "acc_00000001",
+			Created:     created.AddDate(0, 0, day).Format(time.RFC3339),
+			Settled:     created.AddDate(0, 0, day+1).Format(time.RFC3339),
+			Amount:      amount,
+			Currency:    "GBP",
+			Merchant:    merchant,
+			Description: description,
+			Category:    category,
+			IsLoad:      amount > 0,
+			Metadata:    map[string]string{},
+		}
+	}
+
+	return &Data{
+		UserID: "user_00000001",
+		Accounts: []mondodomain.Account{
+			{ID: "acc_00000001", Description: "Peter Pan's Account", Created: created},
+		},
+		Balances: map[string]mondodomain.Balance{
+			"acc_00000001": {Balance: 6080, Currency: "GBP", SpendToday: -820},
+		},
+		Transactions: []mondodomain.Transaction{
+			transaction("tx_00000001", 0, 10000, nil, "Top up"),
+			transaction("tx_00000002", 1, -350, coffee, "OZONE COFFEE ROASTERS"),
+			transaction("tx_00000003", 2, -2450, grocer, "THE GROCER"),
+			transaction("tx_00000004", 3, -300, coffee, "OZONE COFFEE ROASTERS"),
+			transaction("tx_00000005", 4, -820, grocer, "THE GROCER"),
+		},
+	}
+}
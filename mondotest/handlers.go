@@ -0,0 +1,422 @@
+package mondotest
+
+import (
+	"fmt"
+	"github.com/icio/mondo/mondodomain"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// route dispatches the request to its handler. Requires the lock.
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case r.Method == "GET" && path == "/ping":
+		writeJSON(w, http.StatusOK, mondodomain.Ping{Ping: "pong"})
+	case r.Method == "GET" && path == "/ping/whoami":
+		s.serveWhoAmI(w, r)
+	case r.Method == "POST" && path == "/oauth2/token":
+		s.serveToken(w, r)
+	case r.Method == "PUT" && strings.HasPrefix(path, "/upload/"):
+		s.serveUpload(w, r, strings.TrimPrefix(path, "/upload/"))
+	case !s.authenticated(w, r):
+		return
+	case r.Method == "POST" && path == "/oauth2/logout":
+		s.accessToken, s.refreshToken = "", ""
+		writeJSON(w, http.StatusOK, struct{}{})
+	case r.Method == "GET" && path == "/accounts":
+		writeJSON(w, http.StatusOK, mondodomain.AccountsResponse{Accounts: s.data.Accounts})
+	case r.Method == "GET" && path == "/balance":
+		s.serveBalance(w, r)
+	case r.Method == "GET" && path == "/transactions":
+		s.serveTransactions(w, r)
+	case r.Method == "GET" && strings.HasPrefix(path, "/transactions/"):
+		s.serveTransaction(w, r, strings.TrimPrefix(path, "/transactions/"))
+	case r.Method == "PATCH" && strings.HasPrefix(path, "/transactions/"):
+		s.serveAnnotate(w, r, strings.TrimPrefix(path, "/transactions/"))
+	case r.Method == "POST" && path == "/feed":
+		s.serveFeed(w, r)
+	case r.Method == "GET" && path == "/webhooks":
+		s.serveWebhooks(w, r)
+	case r.Method == "POST" && path == "/webhooks":
+		s.serveRegisterWebhook(w, r)
+	case r.Method == "DELETE" && strings.HasPrefix(path, "/webhooks/"):
+		s.serveDeleteWebhook(w, r, strings.TrimPrefix(path, "/webhooks/"))
+	case r.Method == "POST" && path == "/attachment/upload":
+		s.serveAttachmentUpload(w, r)
+	case r.Method == "POST" && path == "/attachment/register":
+		s.serveRegisterAttachment(w, r)
+	case r.Method == "POST" && path == "/attachment/deregister":
+		s.serveDeregisterAttachment(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not_found", "", "Not found")
+	}
+}
+
+func (s *Server) serveWhoAmI(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" {
+		writeJSON(w, http.StatusOK, mondodomain.Identity{})
+		return
+	}
+	if !s.authenticated(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, mondodomain.Identity{
+		Authenticated: true,
+		ClientID:      ClientID,
+		UserID:        s.data.UserID,
+	})
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("client_id") != ClientID || r.PostFormValue("client_secret") != ClientSecret {
+		writeError(w, http.StatusUnauthorized, "unauthorized.bad_client_credentials", "invalid_client", "Invalid client credentials")
+		return
+	}
+
+	switch r.PostFormValue("grant_type") {
+	case "refresh_token":
+		if s.refreshToken == "" || r.PostFormValue("refresh_token") != s.refreshToken {
+			writeError(w, http.StatusUnauthorized, "unauthorized.bad_refresh_token", "invalid_grant", "Invalid refresh token")
+			return
+		}
+	case "authorization_code":
+		if r.PostFormValue("code") != AuthCode {
+			writeError(w, http.StatusUnauthorized, "unauthorized.bad_authorization_code", "invalid_grant", "Invalid authorization code")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param.grant_type", "unsupported_grant_type", "Unsupported grant type")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.issueToken())
+}
+
+func (s *Server) serveBalance(w http.ResponseWriter, r *http.Request) {
+	balance, ok := s.data.Balances[r.URL.Query().Get("account_id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found.account", "", "Account not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, balance)
+}
+
+func (s *Server) serveTransactions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	accountID := query.Get("account_id")
+	expand := query.Get("expand[]") == "merchant"
+
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > 100 {
+			writeBadParam(w, "limit")
+			return
+		}
+	}
+
+	// Bounds are either RFC3339 times, or transaction IDs for since.
+	var since, before time.Time
+	sinceID := ""
+	if param := query.Get("since"); param != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, param); err != nil {
+			sinceID = param
+		}
+	}
+	if param := query.Get("before"); param != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339, param); err != nil {
+			writeBadParam(w, "before")
+			return
+		}
+	}
+
+	trans := s.sortedTransactions()
+	if sinceID != "" {
+		found := false
+		for i, tran := range trans {
+			if tran.ID == sinceID {
+				trans, found = trans[i+1:], true
+				break
+			}
+		}
+		if !found {
+			writeBadParam(w, "since")
+			return
+		}
+	}
+
+	page := make([]interface{}, 0, limit)
+	for _, tran := range trans {
+		if tran.AccountID != accountID {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339, tran.Created)
+		if !since.IsZero() && created.Before(since) {
+			continue
+		}
+		if !before.IsZero() && !created.Before(before) {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		page = append(page, render(tran, expand))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transactions": page})
+}
+
+func (s *Server) serveTransaction(w http.ResponseWriter, r *http.Request, id string) {
+	i := s.findTransaction(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "not_found.transaction", "", "Transaction not found")
+		return
+	}
+	expand := r.URL.Query().Get("expand[]") == "merchant"
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transaction": render(s.data.Transactions[i], expand)})
+}
+
+func (s *Server) serveAnnotate(w http.ResponseWriter, r *http.Request, id string) {
+	i := s.findTransaction(id)
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "not_found.transaction", "", "Transaction not found")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "", err.Error())
+		return
+	}
+
+	tran := &s.data.Transactions[i]
+	metadata := make(map[string]string)
+	for key, value := range tran.Metadata {
+		metadata[key] = value
+	}
+	for key, values := range r.PostForm {
+		if !strings.HasPrefix(key, "metadata[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		key = key[len("metadata[") : len(key)-1]
+		if values[0] == "" {
+			delete(metadata, key)
+		} else {
+			metadata[key] = values[0]
+		}
+	}
+	tran.Metadata = metadata
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transaction": render(*tran, false)})
+}
+
+func (s *Server) serveFeed(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "", err.Error())
+		return
+	}
+	item := FeedItem{
+		AccountID: r.PostForm.Get("account_id"),
+		Type:      r.PostForm.Get("type"),
+		URL:       r.PostForm.Get("url"),
+		Params:    make(map[string]string),
+	}
+	for key, values := range r.PostForm {
+		if strings.HasPrefix(key, "params[") && strings.HasSuffix(key, "]") {
+			item.Params[key[len("params["):len(key)-1]] = values[0]
+		}
+	}
+	if !s.hasAccount(item.AccountID) {
+		writeBadParam(w, "account_id")
+		return
+	}
+	if item.Type == "" || item.Params["title"] == "" {
+		writeBadParam(w, "params[title]")
+		return
+	}
+
+	s.feedItems = append(s.feedItems, item)
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+func (s *Server) serveWebhooks(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+	webhooks := make([]mondodomain.Webhook, 0)
+	for _, webhook := range s.data.Webhooks {
+		if webhook.AccountID == accountID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	writeJSON(w, http.StatusOK, mondodomain.WebhooksResponse{Webhooks: webhooks})
+}
+
+func (s *Server) serveRegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook := mondodomain.Webhook{
+		ID:        fmt.Sprintf("webhook_%08d", len(s.data.Webhooks)+1),
+		AccountID: r.PostFormValue("account_id"),
+		URL:       r.PostFormValue("url"),
+	}
+	if !s.hasAccount(webhook.AccountID) {
+		writeBadParam(w, "account_id")
+		return
+	}
+	if webhook.URL == "" {
+		writeBadParam(w, "url")
+		return
+	}
+
+	s.data.Webhooks = append(s.data.Webhooks, webhook)
+	writeJSON(w, http.StatusOK, mondodomain.WebhookResponse{Webhook: webhook})
+}
+
+func (s *Server) serveDeleteWebhook(w http.ResponseWriter, r *http.Request, id string) {
+	for i, webhook := range s.data.Webhooks {
+		if webhook.ID == id {
+			s.data.Webhooks = append(s.data.Webhooks[:i], s.data.Webhooks[i+1:]...)
+			writeJSON(w, http.StatusOK, struct{}{})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found.webhook", "", "Webhook not found")
+}
+
+func (s *Server) serveAttachmentUpload(w http.ResponseWriter, r *http.Request) {
+	fileName := r.PostFormValue("file_name")
+	if fileName == "" {
+		writeBadParam(w, "file_name")
+		return
+	}
+	if r.PostFormValue("file_type") == "" {
+		writeBadParam(w, "file_type")
+		return
+	}
+
+	id := fmt.Sprintf("file_%08d", len(s.uploads)+1)
+	u := upload{id: id, fileURL: s.server.URL + "/files/" + id + "/" + url.PathEscape(fileName)}
+	s.uploads = append(s.uploads, u)
+	writeJSON(w, http.StatusOK, mondodomain.AttachmentUpload{
+		FileURL:   u.fileURL,
+		UploadURL: s.server.URL + "/upload/" + id,
+	})
+}
+
+// serveUpload receives the file of an attachment, as the storage behind the
+// API's upload URLs would, without authentication.
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request, id string) {
+	for i := range s.uploads {
+		if s.uploads[i].id != id {
+			continue
+		}
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.uploads[i].content = content
+		s.uploads[i].uploaded = true
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (s *Server) serveRegisterAttachment(w http.ResponseWriter, r *http.Request) {
+	i := s.findTransaction(r.PostFormValue("external_id"))
+	if i < 0 {
+		writeBadParam(w, "external_id")
+		return
+	}
+	attachment := mondodomain.Attachment{
+		UserID:     s.data.UserID,
+		ExternalID: r.PostFormValue("external_id"),
+		FileURL:    r.PostFormValue("file_url"),
+		FileType:   r.PostFormValue("file_type"),
+		Created:    time.Now().UTC(),
+	}
+	uploaded := false
+	for _, u := range s.uploads {
+		uploaded = uploaded || (u.fileURL == attachment.FileURL && u.uploaded)
+	}
+	if !uploaded {
+		writeBadParam(w, "file_url")
+		return
+	}
+
+	s.attachments++
+	attachment.ID = fmt.Sprintf("attach_%08d", s.attachments)
+	tran := &s.data.Transactions[i]
+	tran.Attachments = append(append([]mondodomain.Attachment(nil), tran.Attachments...), attachment)
+	writeJSON(w, http.StatusOK, mondodomain.AttachmentResponse{Attachment: attachment})
+}
+
+func (s *Server) serveDeregisterAttachment(w http.ResponseWriter, r *http.Request) {
+	id := r.PostFormValue("id")
+	for i := range s.data.Transactions {
+		tran := &s.data.Transactions[i]
+		for j, attachment := range tran.Attachments {
+			if attachment.ID != id {
+				continue
+			}
+			attachments := append([]mondodomain.Attachment(nil), tran.Attachments[:j]...)
+			tran.Attachments = append(attachments, tran.Attachments[j+1:]...)
+			writeJSON(w, http.StatusOK, struct{}{})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found.attachment", "", "Attachment not found")
+}
+
+func writeBadParam(w http.ResponseWriter, param string) {
+	writeError(w, http.StatusBadRequest, "bad_request.bad_param."+param, "", "Bad parameter: "+param)
+}
+
+func (s *Server) hasAccount(id string) bool {
+	for _, account := range s.data.Accounts {
+		if account.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) findTransaction(id string) int {
+	for i, tran := range s.data.Transactions {
+		if tran.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortedTransactions returns the transactions in the order of their creation.
+func (s *Server) sortedTransactions() []mondodomain.Transaction {
+	trans := append([]mondodomain.Transaction(nil), s.data.Transactions...)
+	sort.SliceStable(trans, func(i, j int) bool {
+		a, _ := time.Parse(time.RFC3339, trans[i].Created)
+		b, _ := time.Parse(time.RFC3339, trans[j].Created)
+		return a.Before(b)
+	})
+	return trans
+}
+
+// unexpandedTransaction renders a transaction's merchant as only its ID.
+type unexpandedTransaction struct {
+	mondodomain.Transaction
+	Merchant *string `json:"merchant"`
+}
+
+// render prepares a transaction for encoding, with or without its merchant
+// expanded.
+func render(tran mondodomain.Transaction, expand bool) interface{} {
+	if expand || tran.Merchant == nil {
+		return tran
+	}
+	return unexpandedTransaction{Transaction: tran, Merchant: &tran.Merchant.ID}
+}
@@ -0,0 +1,283 @@
+// Package mondotest provides an in-process fake of the Mondo API, serving an
+// in-memory data set over TLS, for testing code which uses mondo.Client.
+package mondotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondodomain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Default credentials accepted by the fake.
+const (
+	ClientID     string = "oauthclient_test"
+	ClientSecret string = "client_secret_test"
+	AuthCode     string = "auth_code_test"
+)
+
+// Failure is a kind of failure the fake can be scripted to respond with.
+type Failure int
+
+const (
+	// ExpiredToken responds 401 with an invalid_token error, as though the
+	// access token had expired.
+	ExpiredToken Failure = iota
+	// ServerError responds 500.
+	ServerError
+	// RateLimited responds 429 with a Retry-After of 0 seconds.
+	RateLimited
+	// MalformedJSON responds 200 with a truncated JSON body.
+	MalformedJSON
+)
+
+// Server is a fake Mondo API. Its state can be inspected and modified by
+// tests through its exported methods while it serves requests. Thread-safe.
+type Server struct {
+	server *httptest.Server
+
+	lock         sync.Mutex
+	data         *Data
+	accessToken  string
+	refreshToken string
+	tokens       int
+	failures     []scriptedFailure
+	feedItems    []FeedItem
+	uploads      []upload
+	attachments  int
+	requests     []string
+}
+
+// upload is a file which the API has given a URL to be uploaded to.
+type upload struct {
+	id       string
+	fileURL  string
+	content  []byte
+	uploaded bool
+}
+
+type scriptedFailure struct {
+	path    string
+	failure Failure
+}
+
+// NewServer starts a fake API serving the given data, or SeedData if nil. The
+// Server should be closed once finished with.
+func NewServer(data *Data) *Server {
+	if data == nil {
+		data = SeedData()
+	}
+	if data.Balances == nil {
+		data.Balances = make(map[string]mondodomain.Balance)
+	}
+
+	s := &Server{data: data}
+	s.issueToken()
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// URL returns the base URL of the server, e.g. "https://127.0.0.1:1234".
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// HTTPClient returns an HTTP client which directs requests built by mondohttp
+// to the fake, trusting its certificate.
+func (s *Server) HTTPClient() *mondo.HTTPClient {
+	return &mondo.HTTPClient{
+		Client: s.server.Client(),
+		Host:   s.server.Listener.Addr().String(),
+	}
+}
+
+// NewClient returns a mondo.Client for the fake, authenticated with the
+// current access and refresh tokens, and uploading attachments to the fake.
+func (s *Server) NewClient() *mondo.Client {
+	token := s.Token()
+	return &mondo.Client{
+		HTTPClient:   s.HTTPClient(),
+		Auth:         mondo.NewClientAccessTokenAuth(ClientID, ClientSecret, token.TokenType+" "+token.AccessToken, token.RefreshToken),
+		UploadClient: s.server.Client(),
+	}
+}
+
+// Token returns the current valid access and refresh tokens.
+func (s *Server) Token() mondodomain.Token {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.token()
+}
+
+// ExpireToken invalidates the current access token, as though it had expired.
+// The refresh token remains valid. Logging out through the API invalidates
+// both.
+func (s *Server) ExpireToken() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.accessToken = ""
+}
+
+// Fail scripts the next n requests to the path (e.g. "/accounts") to fail.
+// An empty path matches requests to any path. Failures are consumed in the
+// order they were scripted.
+func (s *Server) Fail(path string, failure Failure, n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, scriptedFailure{path, failure})
+	}
+}
+
+// Requests returns the method and path of each request received, e.g.
+// "GET /accounts".
+func (s *Server) Requests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// FeedItems returns the feed items which have been created.
+func (s *Server) FeedItems() []FeedItem {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]FeedItem(nil), s.feedItems...)
+}
+
+// Upload returns the content uploaded for the attachment file URL.
+func (s *Server) Upload(fileURL string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, u := range s.uploads {
+		if u.fileURL == fileURL && u.uploaded {
+			return append([]byte(nil), u.content...), true
+		}
+	}
+	return nil, false
+}
+
+// Data returns a copy of the server's current data.
+func (s *Server) Data() Data {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data := *s.data
+	data.Accounts = append([]mondodomain.Account(nil), data.Accounts...)
+	data.Transactions = append([]mondodomain.Transaction(nil), data.Transactions...)
+	data.Webhooks = append([]mondodomain.Webhook(nil), data.Webhooks...)
+	data.Balances = make(map[string]mondodomain.Balance)
+	for id, balance := range s.data.Balances {
+		data.Balances[id] = balance
+	}
+	return data
+}
+
+// AddTransaction appends a transaction to the data set, as though it had just
+// been made.
+func (s *Server) AddTransaction(tran mondodomain.Transaction) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Transactions = append(s.data.Transactions, tran)
+}
+
+// issueToken rotates the access and refresh tokens. Requires the lock.
+func (s *Server) issueToken() mondodomain.Token {
+	s.tokens++
+	s.accessToken = fmt.Sprintf("access_token_%d", s.tokens)
+	s.refreshToken = fmt.Sprintf("refresh_token_%d", s.tokens)
+	return s.token()
+}
+
+// token describes the current tokens. Requires the lock.
+func (s *Server) token() mondodomain.Token {
+	return mondodomain.Token{
+		AccessToken:  s.accessToken,
+		ClientID:     ClientID,
+		ExpiresIn:    21600,
+		RefreshToken: s.refreshToken,
+		TokenType:    "Bearer",
+		UserID:       s.data.UserID,
+	}
+}
+
+// popFailure removes and returns the first failure scripted for the path.
+// Requires the lock.
+func (s *Server) popFailure(path string) (Failure, bool) {
+	for i, f := range s.failures {
+		if f.path == "" || f.path == path {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			return f.failure, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if failure, ok := s.popFailure(r.URL.Path); ok {
+		writeFailure(w, failure)
+		return
+	}
+	s.route(w, r)
+}
+
+func writeFailure(w http.ResponseWriter, failure Failure) {
+	switch failure {
+	case ExpiredToken:
+		writeError(w, http.StatusUnauthorized, "unauthorized.bad_access_token", "invalid_token", "Access token has expired")
+	case ServerError:
+		writeError(w, http.StatusInternalServerError, "internal_service", "", "Internal server error")
+	case RateLimited:
+		w.Header().Set("Retry-After", "0")
+		writeError(w, http.StatusTooManyRequests, "too_many_requests", "", "Too many requests")
+	case MalformedJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"malformed": `))
+	}
+}
+
+// errorResponse mirrors the format of error responses from the API.
+type errorResponse struct {
+	Code    string            `json:"code"`
+	Error   string            `json:"error,omitempty"`
+	Message string            `json:"message"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, errType, message string) {
+	writeJSON(w, status, errorResponse{Code: code, Error: errType, Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	enc, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(enc)
+}
+
+// authenticated checks the request's access token, writing an error response
+// if it isn't valid. Requires the lock.
+func (s *Server) authenticated(w http.ResponseWriter, r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") || s.accessToken == "" || header[len("Bearer "):] != s.accessToken {
+		writeError(w, http.StatusUnauthorized, "unauthorized.bad_access_token", "invalid_token", "Invalid access token")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+package mondotest_test
+
+import (
+	"context"
+	"errors"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondodomain"
+	"github.com/icio/mondo/mondotest"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServer_Accounts(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	accounts, err := s.NewClient().Accounts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != "acc_00000001" {
+		t.Fatalf("Unexpected accounts: %#v", accounts)
+	}
+}
+
+func TestServer_Transactions(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+
+	trans := make(chan mondodomain.Transaction)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(trans)
+		errs <- client.IterTransactions(trans, nil, "", "acc_00000001", false, "", "", 2)
+	}()
+
+	var ids []string
+	for tran := range trans {
+		ids = append(ids, tran.ID)
+		if tran.Merchant != nil && tran.Merchant.Name != "" {
+			t.Errorf("Expected %s's merchant to be unexpanded", tran.ID)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"tx_00000001", "tx_00000002", "tx_00000003", "tx_00000004", "tx_00000005"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected %q but got %q", expected, ids)
+	}
+}
+
+func TestServer_Transaction(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	tran, err := s.NewClient().Transaction(context.Background(), "tx_00000002", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tran.Merchant == nil || tran.Merchant.Name != "Ozone Coffee Roasters" {
+		t.Fatalf("Expected an expanded merchant but got %#v", tran.Merchant)
+	}
+}
+
+func TestServer_Annotate(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+
+	_, err := client.Annotate(context.Background(), "tx_00000002", map[string]string{"receipt": "yes", "tag": "coffee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tran, err := client.Annotate(context.Background(), "tx_00000002", map[string]string{"tag": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"receipt": "yes"}
+	if !reflect.DeepEqual(tran.Metadata, expected) {
+		t.Fatalf("Expected metadata %v but got %v", expected, tran.Metadata)
+	}
+}
+
+func TestServer_Feed(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	err := s.NewClient().CreateFeedItem(context.Background(), "acc_00000001", "basic", "", map[string]string{"title": "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := s.FeedItems(); len(items) != 1 || items[0].Params["title"] != "Hello" {
+		t.Fatalf("Unexpected feed items: %#v", items)
+	}
+}
+
+func TestServer_EnsureWebhook(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+
+	first, err := client.EnsureWebhook(context.Background(), "acc_00000001", "https://example.com/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.EnsureWebhook(context.Background(), "acc_00000001", "https://example.com/hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *first != *second || len(s.Data().Webhooks) != 1 {
+		t.Fatalf("Expected a single webhook but got %#v", s.Data().Webhooks)
+	}
+
+	if err := client.DeleteWebhook(context.Background(), first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Data().Webhooks) != 0 {
+		t.Fatal("Expected the webhook to be deleted")
+	}
+}
+
+func TestServer_AttachFile(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	ctx := context.Background()
+	attach := func() (*mondodomain.Attachment, error) {
+		return client.AttachNamedFile(ctx, "tx_00000002", "receipt.png", strings.NewReader("receipt"), "image/png")
+	}
+	attachments := func() []mondodomain.Attachment {
+		return s.Data().Transactions[1].Attachments
+	}
+
+	// Uploads pass through the Client's middleware.
+	var methods []string
+	client.Use(func(next mondo.DoFunc) mondo.DoFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			methods = append(methods, req.Method)
+			return next(req)
+		}
+	})
+
+	attachment, err := attach()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.ExternalID != "tx_00000002" || attachment.FileType != "image/png" || !strings.HasSuffix(attachment.FileURL, "/receipt.png") {
+		t.Fatalf("Unexpected attachment %#v", attachment)
+	}
+	if content, ok := s.Upload(attachment.FileURL); !ok || string(content) != "receipt" {
+		t.Fatalf("Expected the file to be uploaded but got %q", content)
+	}
+	if n := len(attachments()); n != 1 {
+		t.Fatalf("Expected 1 attachment but got %d", n)
+	}
+	if expected := []string{"POST", "PUT", "POST"}; !reflect.DeepEqual(methods, expected) {
+		t.Fatalf("Expected requests %q through the middleware but got %q", expected, methods)
+	}
+
+	// A failure at any step leaves the transaction without a new attachment.
+	for _, path := range []string{"/attachment/upload", "/upload/file_00000002", "/attachment/register"} {
+		s.Fail(path, mondotest.ServerError, 1)
+		if _, err := attach(); !errors.Is(err, mondo.ErrServerError) {
+			t.Fatalf("%s: expected a server error but got %v", path, err)
+		}
+		if n := len(attachments()); n != 1 {
+			t.Fatalf("%s: expected 1 attachment but got %d", path, n)
+		}
+	}
+
+	if err := client.DeregisterAttachment(ctx, attachment.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(attachments()); n != 0 {
+		t.Fatalf("Expected the attachment to be removed but got %d", n)
+	}
+	if err := client.DeregisterAttachment(ctx, attachment.ID); !errors.Is(err, mondo.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound but got %v", err)
+	}
+
+	// Files are named after their transaction by default, and limited in
+	// size.
+	if attachment, err = client.AttachFile(ctx, "tx_00000002", strings.NewReader("receipt"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(attachment.FileURL, "/tx_00000002") {
+		t.Fatalf("Expected the file to be named after the transaction but got %q", attachment.FileURL)
+	}
+	large := strings.NewReader(strings.Repeat("x", int(mondo.MaxAttachmentSize)+1))
+	if _, err := client.AttachFile(ctx, "tx_00000002", large, "image/png"); err != mondo.ErrAttachmentTooLarge {
+		t.Fatalf("Expected ErrAttachmentTooLarge but got %v", err)
+	}
+}
+
+func TestServer_ExpiredToken(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+
+	s.ExpireToken()
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Fail("/balance", mondotest.ExpiredToken, 1)
+	if _, err := client.Balance(context.Background(), "acc_00000001"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"GET /accounts", "POST /oauth2/token", "GET /accounts", "GET /balance", "POST /oauth2/token", "GET /balance"}
+	if requests := s.Requests(); !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Expected requests %q but got %q", expected, requests)
+	}
+}
+
+func TestServer_Failures(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+
+	s.Fail("/accounts", mondotest.ServerError, 1)
+	if _, err := client.Accounts(context.Background()); err == nil {
+		t.Fatal("Expected a server error")
+	}
+
+	s.Fail("/accounts", mondotest.MalformedJSON, 1)
+	if _, err := client.Accounts(context.Background()); err == nil {
+		t.Fatal("Expected a decoding error")
+	}
+
+	client.Retry = &mondo.ExponentialBackoff{}
+	s.Fail("/accounts", mondotest.RateLimited, 1)
+	s.Fail("/accounts", mondotest.ServerError, 1)
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatalf("Expected retries to succeed but got: %s", err)
+	}
+}
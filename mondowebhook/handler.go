@@ -0,0 +1,139 @@
+// Package mondowebhook receives the events which Mondo delivers to registered
+// webhook URLs.
+package mondowebhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/icio/mondo/mondodomain"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+// TransactionCreated is the event type delivered when a transaction is made.
+const TransactionCreated string = "transaction.created"
+
+// DefaultMaxBodyBytes is the default limit on the size of delivered events.
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// TransactionHandler is called with the transaction of a transaction.created
+// event. Returning an error causes the event to be rejected, so that Mondo
+// will redeliver it.
+type TransactionHandler func(ctx context.Context, tran mondodomain.Transaction) error
+
+// Handler is an http.Handler decoding webhook events and dispatching them to
+// the callbacks registered with it. Callbacks should be registered before the
+// Handler begins serving requests.
+type Handler struct {
+	// Token, when set, must match the "token" query parameter of the request.
+	// Including a secret token in the registered webhook URL prevents others
+	// from delivering events.
+	Token string
+	// MaxBodyBytes limits the size of request bodies, defaulting to
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// Seen, when set, de-duplicates redelivered transactions by their ID.
+	Seen SeenStore
+	// ErrorLog receives the errors returned by callbacks and the Seen store,
+	// defaulting to the standard logger.
+	ErrorLog *log.Logger
+
+	transactionCreated []TransactionHandler
+}
+
+// OnTransactionCreated registers a callback for transaction.created events.
+// Callbacks are called in the order they were registered.
+func (h *Handler) OnTransactionCreated(callback TransactionHandler) {
+	h.transactionCreated = append(h.transactionCreated, callback)
+}
+
+// ServeHTTP handles a webhook delivery.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(h.Token)) != 1 {
+		http.Error(w, "Invalid token", http.StatusForbidden)
+		return
+	}
+
+	// Read the event.
+	maxBytes := h.MaxBodyBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		}
+		return
+	}
+
+	event := new(mondodomain.WebhookEvent)
+	if err := json.Unmarshal(body, event); err != nil {
+		http.Error(w, "Malformed event", http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case TransactionCreated:
+		h.serveTransactionCreated(w, r, event)
+	default:
+		// Acknowledge events we don't handle so that they aren't redelivered.
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *Handler) serveTransactionCreated(w http.ResponseWriter, r *http.Request, event *mondodomain.WebhookEvent) {
+	tran := mondodomain.Transaction{}
+	if err := json.Unmarshal(event.Data, &tran); err != nil || tran.ID == "" {
+		http.Error(w, "Malformed transaction", http.StatusBadRequest)
+		return
+	}
+
+	// Skip transactions we've already handled.
+	if h.Seen != nil {
+		seen, err := h.Seen.Mark(tran.ID)
+		if err != nil {
+			h.logf("mondowebhook: Failed to mark transaction %s as seen: %s", tran.ID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if seen {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	for _, callback := range h.transactionCreated {
+		if err := callback(r.Context(), tran); err != nil {
+			h.logf("mondowebhook: Failed to handle transaction %s: %s", tran.ID, err)
+			if h.Seen != nil {
+				if err := h.Seen.Forget(tran.ID); err != nil {
+					h.logf("mondowebhook: Failed to forget transaction %s: %s", tran.ID, err)
+				}
+			}
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,117 @@
+package mondowebhook
+
+import (
+	"context"
+	"errors"
+	"github.com/icio/mondo/mondodomain"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const createdEvent = `{
+	"type": "transaction.created",
+	"data": {
+		"account_id": "acc_123",
+		"amount": -350,
+		"created": "2015-09-04T14:28:40Z",
+		"currency": "GBP",
+		"description": "Ozone Coffee Roasters",
+		"id": "tx_456",
+		"merchant": "merch_789"
+	}
+}`
+
+func deliver(h *Handler, target, body string) int {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", target, strings.NewReader(body)))
+	return w.Code
+}
+
+func TestHandler_TransactionCreated(t *testing.T) {
+	var received []mondodomain.Transaction
+	h := &Handler{Seen: &MemorySeenStore{}}
+	h.OnTransactionCreated(func(ctx context.Context, tran mondodomain.Transaction) error {
+		received = append(received, tran)
+		return nil
+	})
+
+	if code := deliver(h, "/hook", createdEvent); code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %d", code)
+	}
+	if code := deliver(h, "/hook", createdEvent); code != http.StatusOK {
+		t.Fatalf("Expected redelivery to return 200 but got %d", code)
+	}
+
+	if len(received) != 1 {
+		t.Fatalf("Expected one transaction but got %d", len(received))
+	}
+	if received[0].ID != "tx_456" || received[0].AccountID != "acc_123" || received[0].Amount != -350 || received[0].Merchant.ID != "merch_789" {
+		t.Fatalf("Unexpected transaction decoded: %#v", received[0])
+	}
+}
+
+func TestHandler_CallbackError(t *testing.T) {
+	calls := 0
+	h := &Handler{Seen: &MemorySeenStore{}, ErrorLog: log.New(ioutil.Discard, "", 0)}
+	h.OnTransactionCreated(func(ctx context.Context, tran mondodomain.Transaction) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+
+	if code := deliver(h, "/hook", createdEvent); code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 but got %d", code)
+	}
+	if code := deliver(h, "/hook", createdEvent); code != http.StatusOK {
+		t.Fatalf("Expected redelivery to succeed but got %d", code)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected the failed transaction to be handled again but got %d calls", calls)
+	}
+}
+
+func TestHandler_Rejections(t *testing.T) {
+	h := &Handler{Token: "s3cret", MaxBodyBytes: 512}
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		code   int
+	}{
+		{"valid", "/hook?token=s3cret", createdEvent, http.StatusOK},
+		{"missing token", "/hook", createdEvent, http.StatusForbidden},
+		{"wrong token", "/hook?token=guess", createdEvent, http.StatusForbidden},
+		{"malformed", "/hook?token=s3cret", `{"type": `, http.StatusBadRequest},
+		{"missing id", "/hook?token=s3cret", `{"type": "transaction.created", "data": {}}`, http.StatusBadRequest},
+		{"oversized", "/hook?token=s3cret", `{"type": "` + strings.Repeat("x", 512) + `"}`, http.StatusRequestEntityTooLarge},
+		{"unknown type", "/hook?token=s3cret", `{"type": "account.closed", "data": {}}`, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		if code := deliver(h, test.target, test.body); code != test.code {
+			t.Errorf("%s: Expected %d but got %d", test.name, test.code, code)
+		}
+	}
+}
+
+func TestMemorySeenStore_Evicts(t *testing.T) {
+	s := &MemorySeenStore{Size: 2}
+	for _, id := range []string{"a", "b", "c"} {
+		if seen, _ := s.Mark(id); seen {
+			t.Fatalf("Expected %s to be unseen", id)
+		}
+	}
+	if seen, _ := s.Mark("a"); seen {
+		t.Fatal("Expected a to have been evicted")
+	}
+	if seen, _ := s.Mark("c"); !seen {
+		t.Fatal("Expected c to have been seen")
+	}
+}
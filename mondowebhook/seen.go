@@ -0,0 +1,70 @@
+package mondowebhook
+
+import "sync"
+
+// DefaultSeenSize is the default number of IDs remembered by MemorySeenStore.
+const DefaultSeenSize int = 10000
+
+// SeenStore records the IDs of the transactions already handled, allowing
+// Handler to ignore redeliveries. Implementations must be thread-safe.
+type SeenStore interface {
+	// Mark records the ID, reporting whether it was already recorded.
+	Mark(id string) (seen bool, err error)
+	// Forget removes the ID, so that its redelivery is handled again.
+	Forget(id string) error
+}
+
+// MemorySeenStore is a SeenStore remembering the most recently marked IDs in
+// memory. Thread-safe.
+type MemorySeenStore struct {
+	// Size is the number of IDs remembered, defaulting to DefaultSeenSize.
+	Size int
+
+	lock  sync.Mutex
+	ids   map[string]struct{}
+	order []string
+}
+
+// Mark satisfies SeenStore, evicting the oldest ID when full.
+func (s *MemorySeenStore) Mark(id string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, seen := s.ids[id]; seen {
+		return true, nil
+	}
+	if s.ids == nil {
+		s.ids = make(map[string]struct{})
+	}
+
+	size := s.Size
+	if size <= 0 {
+		size = DefaultSeenSize
+	}
+	for len(s.order) >= size {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.ids[id] = struct{}{}
+	s.order = append(s.order, id)
+	return false, nil
+}
+
+// Forget satisfies SeenStore.
+func (s *MemorySeenStore) Forget(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, seen := s.ids[id]; !seen {
+		return nil
+	}
+	delete(s.ids, id)
+	for i, other := range s.order {
+		if other == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
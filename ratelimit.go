@@ -0,0 +1,169 @@
+package mondo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets is the number of per-token buckets above which full buckets
+// are discarded.
+const maxIdleBuckets = 1024
+
+// RateLimiter is a token-bucket rate limiter which blocks requests until a
+// slot is available, allowing many goroutines sharing a Client to cooperate.
+// Each request draws from a global bucket and from a bucket for its access
+// token. A zero rate disables the corresponding bucket. Thread-safe.
+type RateLimiter struct {
+	// Rate and Burst limit the requests per second made across all tokens.
+	Rate  float64
+	Burst int
+	// PerTokenRate and PerTokenBurst limit the requests per second made with
+	// any single access token.
+	PerTokenRate  float64
+	PerTokenBurst int
+
+	lock   sync.Mutex
+	global bucket
+	tokens map[string]*bucket
+	stats  LimiterStats
+}
+
+// LimiterStats describes the waiting RateLimiter has imposed on requests.
+type LimiterStats struct {
+	// Requests is the number of requests admitted.
+	Requests uint64
+	// Delayed is the number of admitted requests which had to wait.
+	Delayed uint64
+	// Waiting is the number of requests currently blocked.
+	Waiting int
+	// TotalWait and MaxWait summarise the time admitted requests waited.
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// Wait blocks until the request made with the given Authorization header may
+// proceed, or until the context is done.
+func (l *RateLimiter) Wait(ctx context.Context, authorization string) error {
+	key := ""
+	if authorization != "" && l.PerTokenRate > 0 {
+		sum := sha256.Sum256([]byte(authorization))
+		key = hex.EncodeToString(sum[:16])
+	}
+
+	l.lock.Lock()
+	now := time.Now()
+	wait := l.reserve(now, key, 1)
+	if wait <= 0 {
+		l.stats.Requests++
+		l.lock.Unlock()
+		return nil
+	}
+	l.stats.Waiting++
+	l.lock.Unlock()
+
+	err := sleep(ctx, wait)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.stats.Waiting--
+	if err != nil {
+		// Return the slots we didn't use.
+		l.reserve(time.Now(), key, -1)
+		return err
+	}
+	l.stats.Requests++
+	l.stats.Delayed++
+	l.stats.TotalWait += wait
+	if wait > l.stats.MaxWait {
+		l.stats.MaxWait = wait
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the limiter's wait statistics.
+func (l *RateLimiter) Stats() LimiterStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.stats
+}
+
+// reserve takes n slots from the global bucket and the key's bucket, returning
+// how long to wait until they're available. Requires the lock.
+func (l *RateLimiter) reserve(now time.Time, key string, n float64) time.Duration {
+	var wait time.Duration
+	if l.Rate > 0 {
+		wait = l.global.take(now, l.Rate, l.Burst, n)
+	}
+	if key == "" {
+		return wait
+	}
+
+	if l.tokens == nil {
+		l.tokens = make(map[string]*bucket)
+	}
+	b, ok := l.tokens[key]
+	if !ok {
+		if len(l.tokens) >= maxIdleBuckets {
+			l.prune(now)
+		}
+		b = &bucket{}
+		l.tokens[key] = b
+	}
+	if tokenWait := b.take(now, l.PerTokenRate, l.PerTokenBurst, n); tokenWait > wait {
+		wait = tokenWait
+	}
+	return wait
+}
+
+// prune discards the per-token buckets which have refilled completely, as
+// they're indistinguishable from new ones. Requires the lock.
+func (l *RateLimiter) prune(now time.Time) {
+	for key, b := range l.tokens {
+		if b.take(now, l.PerTokenRate, l.PerTokenBurst, 0) == 0 && b.tokens >= float64(burst(l.PerTokenBurst)) {
+			delete(l.tokens, key)
+		}
+	}
+}
+
+// bucket holds the available slots of a token bucket. The slot count goes
+// negative when requests are waiting on future slots.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket then removes n slots, returning how long to wait
+// until the slots will have been available.
+func (b *bucket) take(now time.Time, rate float64, size int, n float64) time.Duration {
+	capacity := float64(burst(size))
+	if b.last.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * rate
+	}
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+
+	b.tokens -= n
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+func burst(size int) int {
+	if size < 1 {
+		return 1
+	}
+	return size
+}
@@ -0,0 +1,45 @@
+package mondo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Burst(t *testing.T) {
+	l := &RateLimiter{Rate: 1000, Burst: 3}
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background(), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := l.Stats(); stats.Requests != 3 || stats.Delayed != 0 {
+		t.Fatalf("Expected 3 immediate requests but got %#v", stats)
+	}
+
+	if err := l.Wait(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if stats := l.Stats(); stats.Requests != 4 || stats.Delayed != 1 || stats.MaxWait <= 0 {
+		t.Fatalf("Expected the 4th request to be delayed but got %#v", stats)
+	}
+}
+
+func TestRateLimiter_PerToken(t *testing.T) {
+	l := &RateLimiter{PerTokenRate: 0.001}
+	if err := l.Wait(context.Background(), "Bearer a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(context.Background(), "Bearer b"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "Bearer a"); err != context.DeadlineExceeded {
+		t.Fatalf("Expected the second request for a token to time out but got %v", err)
+	}
+	if stats := l.Stats(); stats.Requests != 2 || stats.Waiting != 0 {
+		t.Fatalf("Expected 2 admitted requests but got %#v", stats)
+	}
+}
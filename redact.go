@@ -0,0 +1,155 @@
+package mondo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values of sensitive headers, fields and parameters
+// when requests are formatted for errors and logs.
+const Redacted = "REDACTED"
+
+// redactedParams are the query parameters and form fields masked by default
+// as they identify the user's accounts and transactions, and may be replaced
+// with SetRedactedQueryParams.
+var redactedParams = struct {
+	sync.RWMutex
+	names []string
+}{names: []string{"account_id", "before", "since"}}
+
+// RedactedQueryParams returns the query parameters and form fields, besides
+// credentials, whose values are masked when requests are formatted for errors
+// and logs.
+func RedactedQueryParams() []string {
+	redactedParams.RLock()
+	defer redactedParams.RUnlock()
+	return append([]string(nil), redactedParams.names...)
+}
+
+// SetRedactedQueryParams replaces the query parameters and form fields whose
+// values are masked, to suit the application. Credentials are always masked.
+// Safe to call concurrently with requests.
+func SetRedactedQueryParams(names ...string) {
+	redactedParams.Lock()
+	defer redactedParams.Unlock()
+	redactedParams.names = append([]string(nil), names...)
+}
+
+// redactedFields are the query parameters and form fields which are always
+// masked, as they carry credentials.
+var redactedFields = []string{"access_token", "client_secret", "code", "password", "refresh_token"}
+
+// redactedHeaders are the headers whose values are always masked.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// RedactURL returns the URL as a string with the values of credentials and
+// RedactedQueryParams masked. IDs in the URL's path are left as they are.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	if _, hasPassword := u.User.Password(); hasPassword {
+		redacted.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	if u.RawQuery != "" {
+		redacted.RawQuery = redactValues(u.Query(), RedactedQueryParams()).Encode()
+	}
+	return redacted.String()
+}
+
+// DumpRequest returns the HTTP/1.x wire representation of the request, as
+// httputil.DumpRequestOut, with the values of credentials and
+// RedactedQueryParams masked in its URL and form body. The body is only
+// included if it can be read without consuming it, i.e. through GetBody.
+func DumpRequest(req *http.Request) ([]byte, error) {
+	dump := req.Clone(req.Context())
+	dump.URL, _ = url.Parse(RedactURL(req.URL))
+	dump.Header = redactHeader(req.Header)
+	dump.Body = nil
+	dump.GetBody = nil
+	dump.ContentLength = 0
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, WrapError(err, "Failed to read request body", req, nil)
+		}
+		defer body.Close()
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, WrapError(err, "Failed to read request body", req, nil)
+		}
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			if form, err := url.ParseQuery(string(content)); err == nil {
+				content = []byte(redactValues(form, RedactedQueryParams()).Encode())
+			}
+		}
+		dump.Body = ioutil.NopCloser(bytes.NewReader(content))
+		dump.ContentLength = int64(len(content))
+	}
+
+	return httputil.DumpRequestOut(dump, dump.Body != nil)
+}
+
+// redactValues returns a copy of the values with those of redactedFields and
+// the given names masked.
+func redactValues(values url.Values, names []string) url.Values {
+	redacted := make(url.Values, len(values))
+	for key, vals := range values {
+		if contains(redactedFields, key) || contains(names, key) {
+			vals = []string{Redacted}
+		}
+		redacted[key] = vals
+	}
+	return redacted
+}
+
+// redactHeader returns a copy of the header with the values of
+// redactedHeaders masked. Authorization headers keep their scheme.
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, key := range redactedHeaders {
+		vals := redacted.Values(key)
+		if len(vals) == 0 {
+			continue
+		}
+		masked := make([]string, len(vals))
+		for i, val := range vals {
+			masked[i] = Redacted
+			if scheme, _, ok := strings.Cut(val, " "); ok && key == "Authorization" {
+				masked[i] = scheme + " " + Redacted
+			}
+		}
+		redacted[http.CanonicalHeaderKey(key)] = masked
+	}
+	return redacted
+}
+
+// redactError masks the URL of errors returned by http.Client, which would
+// otherwise include any credentials and RedactedQueryParams.
+func redactError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	redacted := *urlErr
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		redacted.URL = RedactURL(u)
+	}
+	return &redacted
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
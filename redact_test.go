@@ -0,0 +1,97 @@
+package mondo
+
+import (
+	"fmt"
+	"github.com/icio/mondo/mondohttp"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	req := mondohttp.NewTransactionsRequest("Bearer abc", "acc_123", true, "tx_456", "", 10)
+
+	redacted := RedactURL(req.URL)
+	if strings.Contains(redacted, "acc_123") || strings.Contains(redacted, "tx_456") {
+		t.Fatalf("Expected account_id and since to be redacted from %q", redacted)
+	}
+	if !strings.Contains(redacted, "limit=10") {
+		t.Fatalf("Expected limit to remain in %q", redacted)
+	}
+}
+
+func TestSetRedactedQueryParams(t *testing.T) {
+	defer SetRedactedQueryParams(RedactedQueryParams()...)
+	SetRedactedQueryParams("limit")
+
+	req := mondohttp.NewTransactionsRequest("Bearer abc", "acc_123", false, "tx_456", "", 10)
+	redacted := RedactURL(req.URL)
+	if !strings.Contains(redacted, "acc_123") || strings.Contains(redacted, "limit=10") {
+		t.Fatalf("Expected only limit to be redacted from %q", redacted)
+	}
+
+	dump, err := DumpRequest(mondohttp.NewRegisterWebhookRequest("Bearer abc", "acc_123", "https://example.com/hook"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dump), "account_id=acc_123") {
+		t.Fatalf("Expected account_id to be kept in:\n%s", dump)
+	}
+}
+
+func TestDumpRequest(t *testing.T) {
+	req := mondohttp.NewRefreshAccessRequest("client", "secret123", "refresh456")
+	req.Header.Set("Authorization", "Bearer abc789")
+
+	dump, err := DumpRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret123", "refresh456", "abc789"} {
+		if strings.Contains(string(dump), secret) {
+			t.Fatalf("Expected %q to be redacted from:\n%s", secret, dump)
+		}
+	}
+	if !strings.Contains(string(dump), "client_id=client") || !strings.Contains(string(dump), "Bearer "+Redacted) {
+		t.Fatalf("Expected client_id and Authorization scheme in:\n%s", dump)
+	}
+
+	// The request itself is left intact for sending.
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "refresh456") || req.Header.Get("Authorization") != "Bearer abc789" {
+		t.Fatal("Expected the request to be unchanged")
+	}
+}
+
+func TestDumpRequest_Form(t *testing.T) {
+	req := mondohttp.NewRegisterWebhookRequest("Bearer abc789", "acc_123", "https://example.com/hook")
+
+	dump, err := DumpRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(dump), "acc_123") || !strings.Contains(string(dump), "account_id="+Redacted) {
+		t.Fatalf("Expected account_id to be redacted from:\n%s", dump)
+	}
+}
+
+func TestError_GoString(t *testing.T) {
+	req := mondohttp.NewBalanceRequest("Bearer abc789", "acc_123")
+	resp := &http.Response{StatusCode: 404, Status: "404 Not Found", Request: req}
+	err := &ResponseError{Request: req, Response: resp, StatusCode: 404, Code: "not_found"}
+
+	for _, format := range []string{"%#v", "%v", "%+v"} {
+		out := fmt.Sprintf(format, err)
+		if strings.Contains(out, "abc789") || strings.Contains(out, "acc_123") {
+			t.Fatalf("Expected %s to redact credentials and account ID, got %q", format, out)
+		}
+	}
+	out := fmt.Sprintf("%#v", WrapError(err, "Failed", req, resp))
+	if strings.Contains(out, "abc789") || strings.Contains(out, "acc_123") {
+		t.Fatalf("Expected %%#v of Error to redact credentials and account ID, got %q", out)
+	}
+}
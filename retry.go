@@ -0,0 +1,154 @@
+package mondo
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errBodyNotRewindable indicates a request can't be replayed as its body has
+// already been consumed.
+var errBodyNotRewindable = errors.New("mondo: Request body cannot be rewound")
+
+// RetryPolicy decides whether a failed attempt at a request should be retried.
+type RetryPolicy interface {
+	// Retry is given the number of attempts made so far and the outcome of
+	// the latest, and reports how long to wait before retrying, if at all.
+	Retry(attempts int, req *http.Request, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// ExponentialBackoff is a RetryPolicy which retries transport errors, 429 and
+// 5xx responses to GET requests, and to PATCH requests when RetryPATCH is set,
+// waiting a jittered and exponentially increasing delay between attempts.
+// Other methods, such as the POSTs and DELETEs which change the user's data,
+// are never retried. Retry-After response headers take precedence over the
+// computed delay, though responses asking us to wait longer than MaxDelay are
+// not retried. The zero value is ready to use.
+type ExponentialBackoff struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Defaults to 3.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. Defaults to 100ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, including those asked for by
+	// Retry-After headers. Defaults to 10s.
+	MaxDelay time.Duration
+	// RetryPATCH opts PATCH requests, such as transaction annotations, into
+	// being retried.
+	RetryPATCH bool
+}
+
+// Retry satisfies RetryPolicy.
+func (b *ExponentialBackoff) Retry(attempts int, req *http.Request, resp *http.Response, err error) (time.Duration, bool) {
+	maxAttempts := b.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 3
+	}
+	if attempts >= maxAttempts || !b.retries(req.Method) || !rewindable(req) {
+		return 0, false
+	}
+
+	if resp == nil {
+		// Transport errors are retryable unless we were cancelled.
+		return b.delay(attempts), err != nil && req.Context().Err() == nil
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return 0, false
+	}
+	if wait, ok := retryAfter(resp); ok {
+		return wait, wait <= b.maxDelay()
+	}
+	return b.delay(attempts), true
+}
+
+// retries reports whether requests of the method may be retried.
+func (b *ExponentialBackoff) retries(method string) bool {
+	switch method {
+	case "GET":
+		return true
+	case "PATCH":
+		return b.RetryPATCH
+	}
+	return false
+}
+
+// delay returns a duration in [d/2, d) where d doubles with each attempt.
+func (b *ExponentialBackoff) delay(attempts int) time.Duration {
+	base, maxDelay := b.BaseDelay, b.maxDelay()
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+
+	d := base
+	for i := 1; i < attempts && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (b *ExponentialBackoff) maxDelay() time.Duration {
+	if b.MaxDelay <= 0 {
+		return 10 * time.Second
+	}
+	return b.MaxDelay
+}
+
+// retryAfter parses the Retry-After header of a response, given either as a
+// number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		wait := at.Sub(time.Now())
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// rewindable reports whether the request's body can be replayed.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindBody resets the request's body so that it can be sent again.
+func rewindBody(req *http.Request) error {
+	if req.GetBody == nil {
+		if !rewindable(req) {
+			return errBodyNotRewindable
+		}
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// sleep waits for the given duration, returning early with the context's
+// error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,114 @@
+package mondo
+
+import (
+	"github.com/icio/mondo/mondohttp"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedClient responds to each request with the next status in its script,
+// recording the request bodies it received.
+type scriptedClient struct {
+	statuses []int
+	bodies   []string
+}
+
+func (c *scriptedClient) Do(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, _ := ioutil.ReadAll(req.Body)
+		body = string(b)
+	}
+	c.bodies = append(c.bodies, body)
+
+	status := c.statuses[0]
+	c.statuses = c.statuses[1:]
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     http.Header{"Retry-After": {"0"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message": "nope"}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestExponentialBackoff_RetriesGET(t *testing.T) {
+	httpClient := &scriptedClient{statuses: []int{503, 429, 200}}
+	client := &Client{HTTPClient: httpClient, Retry: &ExponentialBackoff{}}
+
+	_, err := client.Do(mondohttp.NewPingRequest())
+	if err != nil {
+		t.Fatalf("Expected success after retries but got: %s", err)
+	}
+	if len(httpClient.bodies) != 3 {
+		t.Fatalf("Expected 3 attempts but got %d", len(httpClient.bodies))
+	}
+}
+
+func TestExponentialBackoff_GivesUp(t *testing.T) {
+	httpClient := &scriptedClient{statuses: []int{500, 500, 500, 200}}
+	client := &Client{HTTPClient: httpClient, Retry: &ExponentialBackoff{MaxAttempts: 3}}
+
+	_, err := client.Do(mondohttp.NewPingRequest())
+	if _, ok := err.(*ResponseError); !ok {
+		t.Fatalf("Expected a ResponseError but got: %#v", err)
+	}
+	if len(httpClient.bodies) != 3 {
+		t.Fatalf("Expected 3 attempts but got %d", len(httpClient.bodies))
+	}
+}
+
+func TestExponentialBackoff_SkipsPOST(t *testing.T) {
+	for _, req := range []*http.Request{
+		mondohttp.NewCreateURLFeedItemRequest("", "acc_123", "http://a.com", "Title", "http://a.com/a.png"),
+		mondohttp.NewDeleteWebhookRequest("", "webhook_123"),
+	} {
+		httpClient := &scriptedClient{statuses: []int{503, 200}}
+		client := &Client{HTTPClient: httpClient, Retry: &ExponentialBackoff{}}
+
+		if _, err := client.Do(req); err == nil {
+			t.Fatalf("Expected the %s not to be retried", req.Method)
+		}
+		if len(httpClient.bodies) != 1 {
+			t.Fatalf("Expected 1 %s attempt but got %d", req.Method, len(httpClient.bodies))
+		}
+	}
+}
+
+func TestExponentialBackoff_ReplaysPATCH(t *testing.T) {
+	httpClient := &scriptedClient{statuses: []int{502, 200}}
+	client := &Client{HTTPClient: httpClient, Retry: &ExponentialBackoff{RetryPATCH: true}}
+
+	_, err := client.Do(mondohttp.NewAnnotateTransactionRequest("", "tx_123", map[string]string{"a": "b"}))
+	if err != nil {
+		t.Fatalf("Expected success after retries but got: %s", err)
+	}
+	if len(httpClient.bodies) != 2 || httpClient.bodies[0] != httpClient.bodies[1] || httpClient.bodies[1] == "" {
+		t.Fatalf("Expected the body to be replayed but got %q", httpClient.bodies)
+	}
+}
+
+func TestExponentialBackoff_Delay(t *testing.T) {
+	b := &ExponentialBackoff{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+	for attempts, upper := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		d := b.delay(attempts + 1)
+		if d < upper/2 || d > upper {
+			t.Errorf("Expected delay after %d attempts within [%s, %s] but got %s", attempts+1, upper/2, upper, d)
+		}
+	}
+}
+
+func TestExponentialBackoff_RetryAfter(t *testing.T) {
+	b := &ExponentialBackoff{MaxDelay: 5 * time.Second}
+	req := mondohttp.NewPingRequest()
+	for header, expected := range map[string]bool{"5": true, "6": false} {
+		resp := &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": {header}}}
+		wait, retry := b.Retry(1, req, resp, nil)
+		if retry != expected || (retry && wait != 5*time.Second) {
+			t.Errorf("Retry-After %s: expected retry %v but got %v after %s", header, expected, retry, wait)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package mondo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/icio/mondo/internal/atomicfile"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// passphraseIterations is the PBKDF2 work factor for passphrase keys.
+const passphraseIterations = 600000
+
+// minSecretLength is the minimum length of secret keys, in bytes.
+const minSecretLength = 32
+
+// sealedVersion identifies the format of sealed token files.
+const sealedVersion = 1
+
+var (
+	// ErrInsecurePermissions indicates that a token or key file is accessible
+	// to users other than its owner.
+	ErrInsecurePermissions = errors.New("mondo: File is accessible to other users")
+	// ErrUnsealFailed indicates that none of the keys given could decrypt a
+	// sealed token file, or that the file has been tampered with.
+	ErrUnsealFailed = errors.New("mondo: Failed to unseal token")
+)
+
+// SealingKey is the secret from which SealedTokenStore derives the encryption
+// key of each token file: either a passphrase or random key material.
+type SealingKey struct {
+	kdf    string
+	secret []byte
+}
+
+// PassphraseKey creates a SealingKey from a passphrase, stretched with PBKDF2.
+func PassphraseKey(passphrase string) SealingKey {
+	return SealingKey{kdf: "pbkdf2-sha256", secret: []byte(passphrase)}
+}
+
+// SecretKey creates a SealingKey from at least 32 bytes of random key
+// material, expanded with HKDF.
+func SecretKey(secret []byte) (SealingKey, error) {
+	if len(secret) < minSecretLength {
+		return SealingKey{}, fmt.Errorf("mondo: Secret key must be at least %d bytes", minSecretLength)
+	}
+	return SealingKey{kdf: "hkdf-sha256", secret: append([]byte(nil), secret...)}, nil
+}
+
+// LoadSecretKey reads a SecretKey from a file holding the key material in
+// standard base64, such as is written by `head -c 32 /dev/urandom | base64`.
+// Whitespace around the encoded key is ignored. The file must only be
+// accessible to its owner.
+func LoadSecretKey(path string) (SealingKey, error) {
+	if err := checkPermissions(path); err != nil {
+		return SealingKey{}, err
+	}
+	encoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SealingKey{}, err
+	}
+	secret, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return SealingKey{}, fmt.Errorf("mondo: Secret key file is not base64: %s", err)
+	}
+	return SecretKey(secret)
+}
+
+// derive produces the encryption key for a file with the given salt.
+func (k SealingKey) derive(salt []byte) ([]byte, error) {
+	switch k.kdf {
+	case "pbkdf2-sha256":
+		return pbkdf2.Key(sha256.New, string(k.secret), salt, passphraseIterations, 32)
+	case "hkdf-sha256":
+		return hkdf.Key(sha256.New, k.secret, salt, "mondo token store", 32)
+	}
+	return nil, errors.New("mondo: Uninitialised SealingKey")
+}
+
+// sealedFile is the format of SealedTokenStore files.
+type sealedFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Sealed  []byte `json:"sealed"`
+}
+
+// additionalData binds the file's parameters to its ciphertext.
+func (f *sealedFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("mondo/%d/%s", f.Version, f.KDF))
+}
+
+// SealedTokenStore is a TokenStore keeping the token in a file encrypted with
+// AES-256-GCM, under a key derived from Key. Files readable by users other
+// than their owner are refused. Files sealed with one of the OldKeys are
+// re-sealed with Key when loaded, allowing keys to be rotated. As with
+// FileTokenStore, files are guarded against other processes on Unix systems.
+// Thread-safe.
+type SealedTokenStore struct {
+	Path    string
+	Key     SealingKey
+	OldKeys []SealingKey
+
+	lock sync.Mutex
+	// derived caches derived keys by KDF, secret and salt, sparing repeated
+	// passphrase stretching.
+	derived map[string][]byte
+}
+
+// NewSealedTokenStore prepares a store for the sealed token file at the given
+// path.
+func NewSealedTokenStore(path string, key SealingKey, oldKeys ...SealingKey) *SealedTokenStore {
+	return &SealedTokenStore{Path: path, Key: key, OldKeys: oldKeys}
+}
+
+// Load satisfies TokenStore.
+func (s *SealedTokenStore) Load() (*StoredToken, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	unlock, err := lockFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return s.load()
+}
+
+// CompareAndSwap satisfies TokenStore.
+func (s *SealedTokenStore) CompareAndSwap(prev, next *StoredToken) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	unlock, err := lockFile(s.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := s.load()
+	if err != nil {
+		return err
+	}
+	if !sameToken(current, prev) {
+		return ErrTokenConflict
+	}
+
+	if next == nil {
+		err = os.Remove(s.Path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return s.save(next)
+}
+
+// load reads and unseals the token, re-sealing it if it was sealed with an old
+// key. Requires the lock.
+func (s *SealedTokenStore) load() (*StoredToken, error) {
+	if err := checkPermissions(s.Path); os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := new(sealedFile)
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+	if file.Version != sealedVersion {
+		return nil, fmt.Errorf("mondo: Unsupported sealed token version %d", file.Version)
+	}
+
+	for i, key := range append([]SealingKey{s.Key}, s.OldKeys...) {
+		if key.kdf != file.KDF {
+			continue
+		}
+		plain, err := s.unseal(key, file)
+		if err != nil {
+			continue
+		}
+
+		token := new(StoredToken)
+		if err := json.Unmarshal(plain, token); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			if err := s.save(token); err != nil {
+				return nil, err
+			}
+		}
+		return token, nil
+	}
+	return nil, ErrUnsealFailed
+}
+
+// save seals the token with Key and writes it to the file. Requires the lock.
+func (s *SealedTokenStore) save(token *StoredToken) error {
+	plain, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	file := &sealedFile{
+		Version: sealedVersion,
+		KDF:     s.Key.kdf,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	aead, err := s.newAEAD(s.Key, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Sealed = aead.Seal(nil, file.Nonce, plain, file.additionalData())
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.Path, data, 0600)
+}
+
+// unseal decrypts the file's token with the key. Requires the lock.
+func (s *SealedTokenStore) unseal(key SealingKey, file *sealedFile) ([]byte, error) {
+	aead, err := s.newAEAD(key, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, ErrUnsealFailed
+	}
+	return aead.Open(nil, file.Nonce, file.Sealed, file.additionalData())
+}
+
+// newAEAD prepares the cipher for a file with the given salt. Requires the
+// lock.
+func (s *SealedTokenStore) newAEAD(key SealingKey, salt []byte) (cipher.AEAD, error) {
+	cacheKey := key.kdf + "\x00" + string(key.secret) + "\x00" + string(salt)
+	derived, ok := s.derived[cacheKey]
+	if !ok {
+		var err error
+		if derived, err = key.derive(salt); err != nil {
+			return nil, err
+		}
+		if s.derived == nil || len(s.derived) >= 8 {
+			s.derived = make(map[string][]byte)
+		}
+		s.derived[cacheKey] = derived
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// checkPermissions returns ErrInsecurePermissions if the file is accessible
+// to users other than its owner. Permissions aren't checked on Windows, where
+// they aren't reflected in the file mode.
+func checkPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return ErrInsecurePermissions
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package mondo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempTokenPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mondo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "token.sealed")
+}
+
+func mustSecretKey(t *testing.T, b byte) SealingKey {
+	key, err := SecretKey(bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSealedTokenStore_RoundTrip(t *testing.T) {
+	path := tempTokenPath(t)
+	token := &StoredToken{AccessToken: "Bearer secret_access", RefreshToken: "secret_refresh"}
+
+	for _, key := range []SealingKey{mustSecretKey(t, 1), PassphraseKey("correct horse battery staple")} {
+		os.Remove(path)
+		store := NewSealedTokenStore(path, key)
+		if err := store.CompareAndSwap(nil, token); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("secret_")) {
+			t.Fatalf("Expected the token to be sealed but got %s", data)
+		}
+
+		loaded, err := NewSealedTokenStore(path, key).Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameToken(loaded, token) {
+			t.Fatalf("Expected %#v but loaded %#v", token, loaded)
+		}
+	}
+}
+
+func TestSealedTokenStore_WrongKey(t *testing.T) {
+	path := tempTokenPath(t)
+	if err := NewSealedTokenStore(path, mustSecretKey(t, 1)).CompareAndSwap(nil, &StoredToken{AccessToken: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSealedTokenStore(path, mustSecretKey(t, 2)).Load(); err != ErrUnsealFailed {
+		t.Fatalf("Expected ErrUnsealFailed but got %v", err)
+	}
+}
+
+func TestSealedTokenStore_Rotation(t *testing.T) {
+	path := tempTokenPath(t)
+	oldKey, newKey := mustSecretKey(t, 1), mustSecretKey(t, 2)
+	token := &StoredToken{AccessToken: "Bearer a", RefreshToken: "r"}
+	if err := NewSealedTokenStore(path, oldKey).CompareAndSwap(nil, token); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewSealedTokenStore(path, newKey, oldKey).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameToken(loaded, token) {
+		t.Fatalf("Expected %#v but loaded %#v", token, loaded)
+	}
+
+	// The file has been re-sealed with the new key.
+	if _, err := NewSealedTokenStore(path, newKey).Load(); err != nil {
+		t.Fatalf("Expected the token to be re-sealed but got %v", err)
+	}
+}
+
+func TestSealedTokenStore_Permissions(t *testing.T) {
+	path := tempTokenPath(t)
+	store := NewSealedTokenStore(path, mustSecretKey(t, 1))
+	if err := store.CompareAndSwap(nil, &StoredToken{AccessToken: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err != ErrInsecurePermissions {
+		t.Fatalf("Expected ErrInsecurePermissions but got %v", err)
+	}
+}
+
+func TestLoadSecretKey(t *testing.T) {
+	// Key material may begin or end with bytes which look like whitespace.
+	secret := append([]byte{'\n', ' '}, bytes.Repeat([]byte{7}, 30)...)
+	secret = append(secret, '\t')
+	path := tempTokenPath(t)
+	if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(secret)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := LoadSecretKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key.secret, secret) {
+		t.Fatalf("Expected the key %q but got %q", secret, key.secret)
+	}
+
+	if err := ioutil.WriteFile(path, secret, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSecretKey(path); err == nil {
+		t.Fatal("Expected an error loading an unencoded key")
+	}
+}
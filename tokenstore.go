@@ -0,0 +1,160 @@
+package mondo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/icio/mondo/internal/atomicfile"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrTokenConflict is returned by TokenStore.CompareAndSwap when the stored
+// token is not the one expected, e.g. because another process refreshed it.
+var ErrTokenConflict = errors.New("mondo: Stored token has changed")
+
+// StoredToken is the persisted form of a UserAuth's credentials.
+type StoredToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// TokenStore persists the credentials of a UserAuth, so that refreshed tokens
+// survive restarts. Implementations must be thread-safe.
+type TokenStore interface {
+	// Load returns the stored token, or nil if there is none.
+	Load() (*StoredToken, error)
+	// CompareAndSwap replaces the stored token with next if the stored token
+	// equals prev, and returns ErrTokenConflict otherwise. A nil prev expects
+	// there to be no stored token, and a nil next deletes the stored token.
+	CompareAndSwap(prev, next *StoredToken) error
+}
+
+// NewStoredAuth prepares an auth whose tokens are loaded from and saved to the
+// given store, using the client credentials to refresh them.
+func NewStoredAuth(clientID, clientSecret string, store TokenStore) *UserAuth {
+	return &UserAuth{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Store:        store,
+	}
+}
+
+// sameToken reports whether two, possibly nil, tokens are equal.
+func sameToken(a, b *StoredToken) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.AccessToken == b.AccessToken && a.RefreshToken == b.RefreshToken && a.Expiry.Equal(b.Expiry)
+}
+
+// copyToken returns a copy of the token, so that stores don't share memory
+// with their callers.
+func copyToken(token *StoredToken) *StoredToken {
+	if token == nil {
+		return nil
+	}
+	c := *token
+	return &c
+}
+
+// MemoryTokenStore is a TokenStore holding the token in memory, for tests.
+// Thread-safe.
+type MemoryTokenStore struct {
+	lock  sync.Mutex
+	token *StoredToken
+}
+
+// Load satisfies TokenStore.
+func (s *MemoryTokenStore) Load() (*StoredToken, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return copyToken(s.token), nil
+}
+
+// CompareAndSwap satisfies TokenStore.
+func (s *MemoryTokenStore) CompareAndSwap(prev, next *StoredToken) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !sameToken(s.token, prev) {
+		return ErrTokenConflict
+	}
+	s.token = copyToken(next)
+	return nil
+}
+
+// FileTokenStore is a TokenStore keeping the token in a JSON file, readable
+// only by its owner. Files are replaced atomically, and on Unix systems
+// guarded against other processes with an advisory lock on Path+".lock".
+// Thread-safe.
+type FileTokenStore struct {
+	Path string
+
+	lock sync.Mutex
+}
+
+// NewFileTokenStore prepares a store for the token file at the given path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+// Load satisfies TokenStore.
+func (s *FileTokenStore) Load() (*StoredToken, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	unlock, err := lockFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return s.load()
+}
+
+// CompareAndSwap satisfies TokenStore.
+func (s *FileTokenStore) CompareAndSwap(prev, next *StoredToken) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	unlock, err := lockFile(s.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := s.load()
+	if err != nil {
+		return err
+	}
+	if !sameToken(current, prev) {
+		return ErrTokenConflict
+	}
+
+	if next == nil {
+		err = os.Remove(s.Path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.Path, data, 0600)
+}
+
+func (s *FileTokenStore) load() (*StoredToken, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	token := new(StoredToken)
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
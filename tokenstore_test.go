@@ -0,0 +1,197 @@
+package mondo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondotest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStore_CompareAndSwap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mondo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := mondo.NewFileTokenStore(filepath.Join(dir, "token.json"))
+
+	if token, err := store.Load(); token != nil || err != nil {
+		t.Fatalf("Expected no token but got %#v, %v", token, err)
+	}
+
+	first := &mondo.StoredToken{AccessToken: "Bearer a", RefreshToken: "r", Expiry: time.Now().Round(0)}
+	if err := store.CompareAndSwap(nil, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompareAndSwap(nil, first); err != mondo.ErrTokenConflict {
+		t.Fatalf("Expected a conflict but got %v", err)
+	}
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Expected permissions 0600 but got %o", perm)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.AccessToken != first.AccessToken || loaded.RefreshToken != first.RefreshToken || !loaded.Expiry.Equal(first.Expiry) {
+		t.Fatalf("Expected %#v but loaded %#v", first, loaded)
+	}
+
+	if err := store.CompareAndSwap(loaded, nil); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := store.Load(); token != nil || err != nil {
+		t.Fatalf("Expected the token to be deleted but got %#v, %v", token, err)
+	}
+}
+
+func TestFileTokenStore_Processes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mondo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token.json")
+
+	// Stores of the same file, standing in for other processes, each count up
+	// the token without losing the others' updates.
+	const stores, increments = 4, 25
+	var wg sync.WaitGroup
+	for i := 0; i < stores; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store := mondo.NewFileTokenStore(path)
+			for n := 0; n < increments; {
+				prev, err := store.Load()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				count := 0
+				if prev != nil {
+					fmt.Sscan(prev.AccessToken, &count)
+				}
+				err = store.CompareAndSwap(prev, &mondo.StoredToken{AccessToken: fmt.Sprint(count + 1)})
+				if err == nil {
+					n++
+				} else if err != mondo.ErrTokenConflict {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	token, err := mondo.NewFileTokenStore(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprint(stores * increments); token.AccessToken != expected {
+		t.Fatalf("Expected a count of %s but got %s", expected, token.AccessToken)
+	}
+}
+
+func TestUserAuth_Store(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	token := s.Token()
+
+	store := &mondo.MemoryTokenStore{}
+	err := store.CompareAndSwap(nil, &mondo.StoredToken{
+		AccessToken:  "Bearer expired",
+		RefreshToken: token.RefreshToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &mondo.Client{
+		HTTPClient: s.HTTPClient(),
+		Auth:       mondo.NewStoredAuth(mondotest.ClientID, mondotest.ClientSecret, store),
+	}
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The refreshed token has been saved.
+	stored, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := s.Token()
+	if stored.AccessToken != "Bearer "+current.AccessToken || stored.RefreshToken != current.RefreshToken || stored.Expiry.IsZero() {
+		t.Fatalf("Expected the refreshed token to be stored but got %#v", stored)
+	}
+
+	// A new auth picks up the stored token without refreshing.
+	client.Auth = mondo.NewStoredAuth(mondotest.ClientID, mondotest.ClientSecret, store)
+	if _, err := client.Accounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests := s.Requests(); len(requests) != 4 {
+		t.Fatalf("Expected no further refreshes but got %q", requests)
+	}
+}
+
+// racingStore has another process save a token just before the next swap.
+type racingStore struct {
+	mondo.MemoryTokenStore
+	other *mondo.StoredToken
+}
+
+func (s *racingStore) CompareAndSwap(prev, next *mondo.StoredToken) error {
+	if s.other != nil {
+		s.MemoryTokenStore.CompareAndSwap(prev, s.other)
+		s.other = nil
+	}
+	return s.MemoryTokenStore.CompareAndSwap(prev, next)
+}
+
+func TestUserAuth_StoreConflict(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	auth := client.Auth.(*mondo.UserAuth)
+	store := &racingStore{}
+	if err := auth.Persist(store); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []mondo.AuthEvent
+	auth.OnEvent = func(event mondo.AuthEvent) {
+		events = append(events, event)
+	}
+
+	// The refreshed token is used despite the conflict, which is reported,
+	// and the other process's token is left in the store.
+	other := &mondo.StoredToken{AccessToken: "Bearer other", RefreshToken: "other"}
+	store.other = other
+	token, err := auth.GetContext(context.Background(), true, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "Bearer "+s.Token().AccessToken {
+		t.Fatalf("Expected the refreshed token but got %q", token)
+	}
+	if len(events) != 2 || events[1].Type != mondo.AuthSaveFailed || !errors.Is(events[1].Err, mondo.ErrTokenConflict) {
+		t.Fatalf("Expected a refresh and conflicting save but got %#v", events)
+	}
+	if stored, _ := store.Load(); stored.AccessToken != other.AccessToken {
+		t.Fatalf("Expected the other token to be kept but got %#v", stored)
+	}
+}
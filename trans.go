@@ -1,15 +1,190 @@
 package mondo
 
 import (
+	"context"
+	"errors"
 	"github.com/icio/mondo/mondodomain"
 	"github.com/icio/mondo/mondohttp"
+	"iter"
+	"time"
 )
 
+// ErrPaginationLoop indicates that the API returned a page ending with the
+// same transaction as the page before it, so iterating further would repeat.
+var ErrPaginationLoop = errors.New("mondo: Transaction pagination loop")
+
+// TransactionsOptions selects the transactions listed by Client.Transactions.
+type TransactionsOptions struct {
+	// AccessToken (with its Bearer prefix) authenticates the requests. It may
+	// be left empty when the Client has an Auth.
+	AccessToken string
+	AccountID   string
+	// ExpandMerchants includes the details of each transaction's merchant.
+	ExpandMerchants bool
+	// Since is the transaction ID or RFC3339 time after which to list
+	// transactions, and Before the RFC3339 time before which to list them.
+	Since  string
+	Before string
+	// SinceTime and BeforeTime bound the transactions by their created time,
+	// from SinceTime inclusive to BeforeTime exclusive. They take precedence
+	// over Since and Before when set.
+	SinceTime  time.Time
+	BeforeTime time.Time
+	// PageSize is the number of transactions requested at a time. The API's
+	// default is used if it is zero.
+	PageSize int
+}
+
+// TransactionIterator pages through transactions as they are requested with
+// Next. It runs no goroutines of its own, so needn't be closed.
+type TransactionIterator struct {
+	client *Client
+	ctx    context.Context
+	opts   TransactionsOptions
+
+	page []mondodomain.Transaction
+	tran *mondodomain.Transaction
+	// lastID is that of the last transaction of the previous page.
+	lastID string
+	done   bool
+	err    error
+}
+
+// Transactions returns an iterator over an account's transactions, oldest
+// first, requesting further pages as it goes. Iteration stops with an error
+// once the context is cancelled, or the API repeats a page (ErrPaginationLoop).
+func (client *Client) Transactions(ctx context.Context, opts TransactionsOptions) *TransactionIterator {
+	if !opts.SinceTime.IsZero() {
+		opts.Since = mondohttp.FormatTime(opts.SinceTime)
+	}
+	if !opts.BeforeTime.IsZero() {
+		opts.Before = mondohttp.FormatTime(opts.BeforeTime)
+	}
+	return &TransactionIterator{client: client, ctx: ctx, opts: opts}
+}
+
+// TransactionsBetween lists an account's transactions created from since
+// (inclusive) until before (exclusive), oldest first. Either bound may be zero
+// to leave it open.
+func (client *Client) TransactionsBetween(ctx context.Context, accountID string, since, before time.Time) ([]mondodomain.Transaction, error) {
+	it := client.Transactions(ctx, TransactionsOptions{
+		AccountID:  accountID,
+		SinceTime:  since,
+		BeforeTime: before,
+	})
+	var trans []mondodomain.Transaction
+	for it.Next() {
+		trans = append(trans, *it.Transaction())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return trans, nil
+}
+
+// Next advances to the next transaction, returning false once there are no
+// more transactions or an error occurs, which is then reported by Err.
+func (it *TransactionIterator) Next() bool {
+	it.tran = nil
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = WrapError(err, "Transaction iteration cancelled", nil, nil)
+			return false
+		}
+		it.fetch()
+	}
+
+	it.tran = &it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// fetch requests the next page of transactions, after the last one seen.
+func (it *TransactionIterator) fetch() {
+	opts := it.opts
+	resp := new(mondodomain.TransactionsResponse)
+	req := mondohttp.NewTransactionsRequest(opts.AccessToken, opts.AccountID, opts.ExpandMerchants, opts.Since, opts.Before, opts.PageSize)
+	if err := it.client.DoIntoContext(it.ctx, req, resp); err != nil {
+		it.err = err
+		return
+	}
+
+	// Drop the transaction we paged from, should the API include it, and stop
+	// if we've exhausted the available transactions.
+	page := resp.Transactions
+	if it.lastID != "" && len(page) > 0 && page[0].ID == it.lastID {
+		page = page[1:]
+	}
+	if len(page) == 0 {
+		it.done = true
+		return
+	}
+
+	// Guard against the API repeating a page, which would have us iterate
+	// forever.
+	lastID := page[len(page)-1].ID
+	if lastID == it.lastID {
+		it.err = WrapError(ErrPaginationLoop, "Transaction iteration stopped", req, nil)
+		return
+	}
+	it.lastID = lastID
+	it.opts.Since = lastID
+
+	// Keep to the time bounds, stopping once we've crossed BeforeTime.
+	filtered := page[:0]
+	for _, tran := range page {
+		created, err := time.Parse(time.RFC3339, tran.Created)
+		if err != nil {
+			filtered = append(filtered, tran)
+			continue
+		}
+		if !opts.BeforeTime.IsZero() && !created.Before(opts.BeforeTime) {
+			it.done = true
+			break
+		}
+		if !opts.SinceTime.IsZero() && created.Before(opts.SinceTime) {
+			continue
+		}
+		filtered = append(filtered, tran)
+	}
+	it.page = filtered
+}
+
+// Transaction returns the transaction Next advanced to.
+func (it *TransactionIterator) Transaction() *mondodomain.Transaction {
+	return it.tran
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
+
+// All returns the remaining transactions for ranging over. Should iteration
+// stop with an error, it is yielded with a nil transaction as the final pair.
+func (it *TransactionIterator) All() iter.Seq2[*mondodomain.Transaction, error] {
+	return func(yield func(*mondodomain.Transaction, error) bool) {
+		for it.Next() {
+			if !yield(it.Transaction(), nil) {
+				return
+			}
+		}
+		if it.err != nil {
+			yield(nil, it.err)
+		}
+	}
+}
+
 // IterTransactions paginates the Mondo API's transactions endpoint, writing
-// the results to the iter channel. The user can interrupt the pagination by
+// the results to the out channel. The user can interrupt the pagination by
 // signalling the kill channel.
+//
+// Deprecated: Use Transactions, which needs no goroutine or channels.
 func (client *Client) IterTransactions(
-	iter chan<- mondodomain.Transaction,
+	out chan<- mondodomain.Transaction,
 	kill <-chan bool,
 	accessToken string,
 	accountID string,
@@ -18,36 +193,51 @@ func (client *Client) IterTransactions(
 	before string,
 	pageLimit int,
 ) error {
+	return client.IterTransactionsContext(context.Background(), out, kill, accessToken, accountID, expandMerchants, since, before, pageLimit)
+}
+
+// IterTransactionsContext paginates transactions as IterTransactions, and
+// additionally stops with an error when the context is cancelled.
+//
+// Deprecated: Use Transactions, which needs no goroutine or channels.
+func (client *Client) IterTransactionsContext(
+	ctx context.Context,
+	out chan<- mondodomain.Transaction,
+	kill <-chan bool,
+	accessToken string,
+	accountID string,
+	expandMerchants bool,
+	since string,
+	before string,
+	pageLimit int,
+) error {
+	it := client.Transactions(ctx, TransactionsOptions{
+		AccessToken:     accessToken,
+		AccountID:       accountID,
+		ExpandMerchants: expandMerchants,
+		Since:           since,
+		Before:          before,
+		PageSize:        pageLimit,
+	})
 	for {
-		// Ensure no kill signal received before making the next request.
+		// Ensure no kill signal received before advancing, which may request
+		// the next page.
 		select {
 		case <-kill:
 			return nil
 		default:
 		}
-
-		// Request the next set of transactions.
-		transColn := new(mondodomain.TransactionsResponse)
-		err := client.DoInto(mondohttp.NewTransactionsRequest(accessToken, accountID, expandMerchants, since, before, pageLimit), transColn)
-		if err != nil {
-			return err
-		}
-
-		// Forward the transactions to the output channel.
-		for _, tran := range transColn.Transactions {
-			select {
-			case iter <- tran:
-			case <-kill:
-				return nil
-			}
+		if !it.Next() {
+			return it.Err()
 		}
 
-		// Stop if we've exhausted the available transactions.
-		if len(transColn.Transactions) == 0 {
+		// Forward the transaction to the output channel.
+		select {
+		case out <- *it.Transaction():
+		case <-kill:
 			return nil
+		case <-ctx.Done():
+			return WrapError(ctx.Err(), "Transaction iteration cancelled", nil, nil)
 		}
-
-		// Update our pointer to the beginning of the next batch.
-		since = transColn.Transactions[len(transColn.Transactions)-1].ID
 	}
 }
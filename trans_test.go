@@ -0,0 +1,160 @@
+package mondo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/icio/mondo"
+	"github.com/icio/mondo/mondotest"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Transactions(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	it := s.NewClient().Transactions(context.Background(), mondo.TransactionsOptions{
+		AccountID:       "acc_00000001",
+		ExpandMerchants: true,
+		PageSize:        2,
+	})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Transaction().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"tx_00000001", "tx_00000002", "tx_00000003", "tx_00000004", "tx_00000005"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected %q but got %q", expected, ids)
+	}
+	if n := len(s.Requests()); n != 4 {
+		t.Fatalf("Expected 4 requests but got %d: %q", n, s.Requests())
+	}
+	if it.Next() {
+		t.Fatal("Expected the iterator to remain exhausted")
+	}
+}
+
+func TestClient_TransactionsAll(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+	client := s.NewClient()
+	opts := mondo.TransactionsOptions{AccountID: "acc_00000001", PageSize: 2}
+
+	// Breaking early requests no further pages.
+	n := 0
+	for tran, err := range client.Transactions(context.Background(), opts).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n++; n == 3 || tran == nil {
+			break
+		}
+	}
+	if requests := s.Requests(); len(requests) != 2 {
+		t.Fatalf("Expected 2 requests but got %q", requests)
+	}
+
+	// Errors are yielded last.
+	s.Fail("/transactions", mondotest.ServerError, 1)
+	var errs []error
+	for tran, err := range client.Transactions(context.Background(), opts).All() {
+		if err != nil {
+			errs = append(errs, err)
+		} else if tran == nil {
+			t.Fatal("Expected a transaction with a nil error")
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], mondo.ErrServerError) {
+		t.Fatalf("Expected a single ErrServerError but got %v", errs)
+	}
+}
+
+func TestClient_TransactionsCancelled(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := s.NewClient().Transactions(ctx, mondo.TransactionsOptions{AccountID: "acc_00000001", PageSize: 2})
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	cancel()
+
+	// The current page is still served, but no more are requested.
+	n := 1
+	for it.Next() {
+		n++
+	}
+	if n != 2 || !errors.Is(it.Err(), context.Canceled) {
+		t.Fatalf("Expected 2 transactions and a cancellation but got %d and %v", n, it.Err())
+	}
+}
+
+func TestClient_TransactionsBetween(t *testing.T) {
+	s := mondotest.NewServer(nil)
+	defer s.Close()
+
+	since := time.Date(2016, 2, 10, 12, 0, 0, 0, time.UTC)
+	trans, err := s.NewClient().TransactionsBetween(context.Background(), "acc_00000001", since, since.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, tran := range trans {
+		ids = append(ids, tran.ID)
+	}
+	expected := []string{"tx_00000002", "tx_00000003"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("Expected %q but got %q", expected, ids)
+	}
+}
+
+func TestClient_TransactionsPaginationLoop(t *testing.T) {
+	// An API which ignores since, always returning the same page.
+	client := &mondo.Client{
+		HTTPClient: mondo.DoFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"transactions": [{"id": "tx_1"}, {"id": "tx_2"}]}`
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		}),
+	}
+
+	it := client.Transactions(context.Background(), mondo.TransactionsOptions{AccountID: "acc_1"})
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != 2 || !errors.Is(it.Err(), mondo.ErrPaginationLoop) {
+		t.Fatalf("Expected 2 transactions and ErrPaginationLoop but got %d and %v", n, it.Err())
+	}
+}
+
+func TestClient_TransactionsInclusiveSince(t *testing.T) {
+	// An API which includes the since transaction in the page after it.
+	pages := map[string]string{
+		"":     `{"transactions": [{"id": "tx_1"}, {"id": "tx_2"}]}`,
+		"tx_2": `{"transactions": [{"id": "tx_2"}]}`,
+	}
+	client := &mondo.Client{
+		HTTPClient: mondo.DoFunc(func(req *http.Request) (*http.Response, error) {
+			body := pages[req.URL.Query().Get("since")]
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		}),
+	}
+
+	trans, err := client.TransactionsBetween(context.Background(), "acc_1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trans) != 2 || trans[0].ID != "tx_1" || trans[1].ID != "tx_2" {
+		t.Fatalf("Expected tx_1 and tx_2 but got %#v", trans)
+	}
+}